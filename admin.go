@@ -0,0 +1,462 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// AdminServices provides access to API services for operators of an Estuary
+// node. They require a token belonging to a user with admin permissions.
+type AdminServices struct {
+	client *AuthedClient
+}
+
+func NewAdminServices(a *AuthedClient) *AdminServices {
+	return &AdminServices{client: a}
+}
+
+// AddMiner prepares a request to add a miner to the list of miners Estuary
+// makes deals with.
+func (s *AdminServices) AddMiner(addr address.Address) *AdminServicesAddMinerReq {
+	return &AdminServicesAddMinerReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/miners/add/" + url.PathEscape(addr.String())),
+	}
+}
+
+type AdminServicesAddMinerReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesAddMinerReq) Context(ctx context.Context) *AdminServicesAddMinerReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesAddMinerReq) Send() error {
+	_, cleanup, err := r.req.post(nil)
+	defer cleanup()
+
+	return err
+}
+
+// RemoveMiner prepares a request to remove a miner from the list of miners
+// Estuary makes deals with.
+func (s *AdminServices) RemoveMiner(addr address.Address) *AdminServicesRemoveMinerReq {
+	return &AdminServicesRemoveMinerReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/miners/rm/" + url.PathEscape(addr.String())),
+	}
+}
+
+type AdminServicesRemoveMinerReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesRemoveMinerReq) Context(ctx context.Context) *AdminServicesRemoveMinerReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesRemoveMinerReq) Send() error {
+	_, cleanup, err := r.req.post(nil)
+	defer cleanup()
+
+	return err
+}
+
+// SuspendMiner prepares a request to stop Estuary making deals with a miner.
+func (s *AdminServices) SuspendMiner(addr address.Address) *AdminServicesSuspendMinerReq {
+	return &AdminServicesSuspendMinerReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/miners/suspend/" + url.PathEscape(addr.String())),
+	}
+}
+
+type AdminServicesSuspendMinerReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Reason string `json:"reason"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesSuspendMinerReq) Context(ctx context.Context) *AdminServicesSuspendMinerReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Reason sets the reason for the suspension, which is shown in the miner's
+// public stats.
+func (r *AdminServicesSuspendMinerReq) Reason(v string) *AdminServicesSuspendMinerReq {
+	r.data.Reason = v
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesSuspendMinerReq) Send() error {
+	_, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// UnsuspendMiner prepares a request to allow Estuary to make deals with a
+// suspended miner again.
+func (s *AdminServices) UnsuspendMiner(addr address.Address) *AdminServicesUnsuspendMinerReq {
+	return &AdminServicesUnsuspendMinerReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/miners/unsuspend/" + url.PathEscape(addr.String())),
+	}
+}
+
+type AdminServicesUnsuspendMinerReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesUnsuspendMinerReq) Context(ctx context.Context) *AdminServicesUnsuspendMinerReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesUnsuspendMinerReq) Send() error {
+	_, cleanup, err := r.req.put(nil)
+	defer cleanup()
+
+	return err
+}
+
+// Users prepares a request for a list of the node's users.
+func (s *AdminServices) Users() *AdminServicesUsersReq {
+	return &AdminServicesUsersReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/users"),
+	}
+}
+
+type AdminServicesUsersReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesUsersReq) Context(ctx context.Context) *AdminServicesUsersReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of users.
+func (r *AdminServicesUsersReq) Send() ([]AdminUser, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []AdminUser
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// CreateInvite prepares a request to create an invite code that allows a new
+// user to register.
+func (s *AdminServices) CreateInvite(code string) *AdminServicesCreateInviteReq {
+	return &AdminServicesCreateInviteReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/invite/" + url.PathEscape(code)),
+	}
+}
+
+type AdminServicesCreateInviteReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesCreateInviteReq) Context(ctx context.Context) *AdminServicesCreateInviteReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the new invite.
+func (r *AdminServicesCreateInviteReq) Send() (*Invite, error) {
+	res, cleanup, err := r.req.post(nil)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data Invite
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Invites prepares a request for a list of invites.
+func (s *AdminServices) Invites() *AdminServicesInvitesReq {
+	return &AdminServicesInvitesReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/invites"),
+	}
+}
+
+type AdminServicesInvitesReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesInvitesReq) Context(ctx context.Context) *AdminServicesInvitesReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of invites.
+func (r *AdminServicesInvitesReq) Send() ([]Invite, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Invite
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// Shuttles prepares a request for a list of the node's shuttles.
+func (s *AdminServices) Shuttles() *AdminServicesShuttlesReq {
+	return &AdminServicesShuttlesReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/shuttle/list"),
+	}
+}
+
+type AdminServicesShuttlesReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesShuttlesReq) Context(ctx context.Context) *AdminServicesShuttlesReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of shuttles.
+func (r *AdminServicesShuttlesReq) Send() ([]Shuttle, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Shuttle
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// InitShuttle prepares a request to register a new shuttle. The response
+// holds the handle and token the shuttle should be configured with.
+func (s *AdminServices) InitShuttle() *AdminServicesInitShuttleReq {
+	return &AdminServicesInitShuttleReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/shuttle/init"),
+	}
+}
+
+type AdminServicesInitShuttleReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesInitShuttleReq) Context(ctx context.Context) *AdminServicesInitShuttleReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the credentials of the new
+// shuttle.
+func (r *AdminServicesInitShuttleReq) Send() (*ShuttleInit, error) {
+	res, cleanup, err := r.req.post(nil)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ShuttleInit
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// PeeringPeers prepares a request for the list of peers the node maintains
+// connections to.
+func (s *AdminServices) PeeringPeers() *AdminServicesPeeringPeersReq {
+	return &AdminServicesPeeringPeersReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/peering/peers"),
+	}
+}
+
+type AdminServicesPeeringPeersReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesPeeringPeersReq) Context(ctx context.Context) *AdminServicesPeeringPeersReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of peering peers.
+func (r *AdminServicesPeeringPeersReq) Send() ([]PeeringPeer, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []PeeringPeer
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// AddPeeringPeers prepares a request to add peers that the node should
+// maintain connections to.
+func (s *AdminServices) AddPeeringPeers(peers ...peer.AddrInfo) *AdminServicesAddPeeringPeersReq {
+	r := &AdminServicesAddPeeringPeersReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/peering/peers"),
+		data:   make([]PeeringPeer, len(peers)),
+	}
+	for i, p := range peers {
+		r.data[i].ID = p.ID.String()
+		r.data[i].Addrs = make([]string, len(p.Addrs))
+		for j, a := range p.Addrs {
+			r.data[i].Addrs[j] = a.String()
+		}
+	}
+	return r
+}
+
+type AdminServicesAddPeeringPeersReq struct {
+	req
+	client *AuthedClient
+	data   []PeeringPeer
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesAddPeeringPeersReq) Context(ctx context.Context) *AdminServicesAddPeeringPeersReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesAddPeeringPeersReq) Send() error {
+	_, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// RemovePeeringPeers prepares a request to remove peers from the list of
+// peers the node maintains connections to.
+func (s *AdminServices) RemovePeeringPeers(ids ...peer.ID) *AdminServicesRemovePeeringPeersReq {
+	r := &AdminServicesRemovePeeringPeersReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/peering/peers"),
+		data:   make([]string, len(ids)),
+	}
+	for i, id := range ids {
+		r.data[i] = id.String()
+	}
+	return r
+}
+
+type AdminServicesRemovePeeringPeersReq struct {
+	req
+	client *AuthedClient
+	data   []string
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesRemovePeeringPeersReq) Context(ctx context.Context) *AdminServicesRemovePeeringPeersReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *AdminServicesRemovePeeringPeersReq) Send() error {
+	_, cleanup, err := r.req.deleteJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// Stats prepares a request for statistics about the node.
+func (s *AdminServices) Stats() *AdminServicesStatsReq {
+	return &AdminServicesStatsReq{
+		client: s.client,
+		req:    s.client.newReq("/admin/stats"),
+	}
+}
+
+type AdminServicesStatsReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *AdminServicesStatsReq) Context(ctx context.Context) *AdminServicesStatsReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the node's statistics.
+func (r *AdminServicesStatsReq) Send() (*AdminStats, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data AdminStats
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
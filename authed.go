@@ -3,9 +3,17 @@ package creek
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -14,62 +22,128 @@ import (
 // AuthedClient is the base client used for interacting with services that
 // require authentication.
 type AuthedClient struct {
-	// never modified once they have been set
-	hc    *http.Client
-	addr  string
-	ua    string
-	token string
+	config
+	tokens TokenSource // never modified once set
 
-	Pins *PinServices
+	Pins        *PinServices
+	Collections *CollectionServices
+	Content     *ContentServices
+	Deals       *DealServices
+	User        *UserServices
+	Admin       *AdminServices
+	Miners      *MinerServices
 }
 
-func (c *AuthedClient) userAgent() string {
-	if c.ua == "" {
-		return DefaultUserAgent
-	}
+func (c *AuthedClient) newReq(path string) req {
+	r := c.config.newReq(path, EndpointAuthed)
+	r.tokens = c.tokens
+	return r
+}
 
-	return DefaultUserAgent + " " + c.ua
+// NewAuthedClient creates a new client that will use the supplied HTTP client
+// and authentication token and connect via the specified API address. See New
+// for the forms the address may take.
+func NewAuthedClient(client *http.Client, addr string, token string) *AuthedClient {
+	c := New(client, addr)
+	return c.WithToken(token)
 }
 
-func (c *AuthedClient) newReq(path string) req {
-	return req{
-		hc:   c.hc,
-		addr: c.addr,
-		path: path,
-		headers: headers{
-			"User-Agent":    c.userAgent(),
-			"Authorization": "Bearer " + c.token,
-		},
-		par: params{},
-	}
+func (c *AuthedClient) initServices() {
+	c.Pins = NewPinServices(c)
+	c.Collections = NewCollectionServices(c)
+	c.Content = NewContentServices(c)
+	c.Deals = NewDealServices(c)
+	c.User = NewUserServices(c)
+	c.Admin = NewAdminServices(c)
+	c.Miners = NewMinerServices(c)
 }
 
-// New creates a new client that will use the supplied HTTP client and connect
-// via the specified API host address.
-func NewAuthedClient(client *http.Client, addr string, token string) *AuthedClient {
-	ac := &AuthedClient{
-		hc:    client,
-		addr:  addr,
-		token: token,
-	}
-	ac.Pins = NewPinServices(ac)
+// WithRetryPolicy creates a copy of the AuthedClient that retries failed
+// requests according to the supplied policy. A nil policy disables retries.
+func (c *AuthedClient) WithRetryPolicy(p *RetryPolicy) *AuthedClient {
+	ac := c.clone()
+	ac.retry = p
 	return ac
 }
 
+// WithRateLimiter creates a copy of the AuthedClient that waits on the
+// supplied rate limiter before sending each request. A nil limiter disables
+// rate limiting.
+func (c *AuthedClient) WithRateLimiter(l *RateLimiter) *AuthedClient {
+	ac := c.clone()
+	ac.limiter = l
+	return ac
+}
+
+func (c *AuthedClient) clone() *AuthedClient {
+	ac := *c
+	ac.initServices()
+	return &ac
+}
+
+// ContentAdd prepares a request to upload content read from r, using name as
+// the file name. The content is streamed from the reader so the request
+// cannot be retried if it fails part way through. Use ContentAddFile or
+// ContentAddReaderAt for uploads that can be retried.
 func (c *AuthedClient) ContentAdd(name string, r io.Reader) *ContentAddReq {
-	return &ContentAddReq{
+	cr := &ContentAddReq{
 		client: c,
 		req:    c.newReq("/content/add"),
 		name:   name,
 		r:      r,
+		size:   readerSize(r),
+	}
+	cr.req.class = EndpointUpload
+	return cr
+}
+
+// ContentAddFile prepares a request to upload the file at path. The file is
+// opened when the request is sent and read again from the beginning if the
+// upload fails and is retried according to the client's retry policy, even
+// if the policy does not enable retries for POST requests. Estuary does not
+// offer a protocol for resuming partial uploads so every retry sends the
+// whole file.
+func (c *AuthedClient) ContentAddFile(path string) *ContentAddReq {
+	cr := &ContentAddReq{
+		client: c,
+		req:    c.newReq("/content/add"),
+		name:   filepath.Base(path),
+		path:   path,
+		size:   -1,
+	}
+	cr.req.class = EndpointUpload
+	cr.req.retryPost = true
+	return cr
+}
+
+// ContentAddReaderAt prepares a request to upload size bytes of content read
+// from ra, using name as the file name. As with ContentAddFile the content is
+// read again from the beginning if the upload is retried.
+func (c *AuthedClient) ContentAddReaderAt(name string, ra io.ReaderAt, size int64) *ContentAddReq {
+	cr := &ContentAddReq{
+		client: c,
+		req:    c.newReq("/content/add"),
+		name:   name,
+		ra:     ra,
+		size:   size,
 	}
+	cr.req.class = EndpointUpload
+	cr.req.retryPost = true
+	return cr
 }
 
 type ContentAddReq struct {
 	req
-	client *AuthedClient
-	name   string
-	r      io.Reader
+	client   *AuthedClient
+	name     string
+	r        io.Reader   // source of content for streamed uploads
+	ra       io.ReaderAt // source of content for replayable uploads
+	path     string      // file to open as the source of replayable uploads
+	size     int64
+	progress func(written, total int64)
+	stats    UploadStats
+	verify   bool
+	shuttle  bool
 }
 
 // Context sets the context to be used during this request.
@@ -78,11 +152,216 @@ func (r *ContentAddReq) Context(ctx context.Context) *ContentAddReq {
 	return r
 }
 
+// Size sets the total size of the content being uploaded, as reported to the
+// progress function. By default the size is determined from the reader when
+// it is an *os.File or has a Len or Size method, otherwise it is unknown.
+func (r *ContentAddReq) Size(n int64) *ContentAddReq {
+	r.size = n
+	return r
+}
+
+// Progress sets a function that is called as the content is uploaded, with
+// the number of bytes written so far and the total size of the content or -1
+// if the size is unknown. The function is called from a separate goroutine.
+// When an upload is retried the number of bytes written starts again from
+// zero.
+func (r *ContentAddReq) Progress(fn func(written, total int64)) *ContentAddReq {
+	r.progress = fn
+	return r
+}
+
+// Stats returns statistics about the upload made by the most recent call to Send.
+func (r *ContentAddReq) Stats() UploadStats {
+	return r.stats
+}
+
+// VerifyCid enables verification of the cid reported by Estuary for the
+// uploaded content. The cid is computed locally as the content is uploaded,
+// as described by ComputeCid, and a *CidMismatchError is returned along with
+// the added content if the cids differ.
+func (r *ContentAddReq) VerifyCid() *ContentAddReq {
+	r.verify = true
+	return r
+}
+
+// ViaShuttle sends the upload directly to one of the storage shuttles listed
+// in the upload endpoints of the user's settings, as reported by Viewer. If
+// no shuttle is listed, the settings cannot be fetched or the upload to the
+// shuttle fails then the content is uploaded to the API host instead. A
+// streamed upload only falls back if none of the content had been read when
+// the shuttle upload failed. Send waits for any read from the content that is
+// in progress when an upload fails to complete.
+func (r *ContentAddReq) ViaShuttle() *ContentAddReq {
+	r.shuttle = true
+	return r
+}
+
 func (r *ContentAddReq) Send() (*AddedContent, error) {
+	if r.path != "" {
+		f, err := os.Open(r.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		r.ra = f
+		r.size = fi.Size()
+	}
+
+	// Use a fixed boundary so that replayed bodies match the content type.
+	mw := multipart.NewWriter(ioutil.Discard)
+	r.req.headers["Content-Type"] = mw.FormDataContentType()
+
+	if r.shuttle {
+		endpoint, err := r.shuttleEndpoint()
+		if err != nil {
+			r.req.logf("could not find a shuttle, uploading to %s instead: %v", r.req.base, err)
+		} else if endpoint != nil {
+			sr := r.req
+			sr.base = endpoint
+			sr.path = ""
+			data, err := r.upload(&sr, mw.Boundary())
+			if err == nil || data != nil {
+				return data, err
+			}
+			// The shuttle attempt has stopped reading the content, so a
+			// streamed upload can be retried if none of it was read.
+			if r.ra == nil && r.stats.Written > 0 {
+				return nil, err
+			}
+			if ctx := r.req.ctx; ctx != nil && ctx.Err() != nil {
+				return nil, err
+			}
+			r.req.logf("upload to shuttle %s failed, uploading to %s instead: %v", endpoint, r.req.base, err)
+		}
+	}
+
+	return r.upload(&r.req, mw.Boundary())
+}
+
+// shuttleEndpoint returns the url of a randomly chosen shuttle upload
+// endpoint, or nil if the user has none.
+func (r *ContentAddReq) shuttleEndpoint() (*url.URL, error) {
+	vr := r.client.Viewer()
+	vr.req.ctx = r.req.ctx
+	v, err := vr.Send()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := v.Settings.UploadEndpoints
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	return url.Parse(endpoints[rand.Intn(len(endpoints))])
+}
+
+// upload sends the content using rq.
+func (r *ContentAddReq) upload(rq *req, boundary string) (*AddedContent, error) {
+	var src *progressReader
+	var tee *cidTee
+	var body *uploadBody
+	newBody := func() (io.ReadCloser, error) {
+		if body != nil {
+			// The previous attempt was abandoned.
+			body.stop()
+			if tee != nil {
+				tee.Abort(errUploadAbandoned)
+			}
+		}
+		content := r.r
+		if r.ra != nil {
+			content = io.NewSectionReader(r.ra, 0, r.size)
+		}
+		if r.verify {
+			tee = newCidTee(content, DagParams{})
+			content = tee
+		}
+		src = &progressReader{r: content, total: r.size, fn: r.progress}
+		body = r.multipartBody(src, boundary)
+		return body, nil
+	}
+
+	start := time.Now()
+	defer func() {
+		// Wait until nothing more can be read from the content so that the
+		// stats are final and the content can be read by another upload.
+		if body != nil {
+			body.stop()
+		}
+		if tee != nil {
+			tee.Abort(errUploadAbandoned)
+		}
+		if src != nil {
+			r.stats = UploadStats{
+				Written: src.Written(),
+				Elapsed: time.Since(start),
+			}
+		}
+	}()
+
+	var res *http.Response
+	var cleanup func()
+	var err error
+	if r.ra != nil {
+		res, cleanup, err = rq.postBody(newBody)
+	} else {
+		b, _ := newBody()
+		res, cleanup, err = rq.post(b)
+	}
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data AddedContent
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	if tee != nil {
+		want, err := tee.Cid()
+		if err != nil {
+			return &data, fmt.Errorf("compute cid: %w", err)
+		}
+		if got, err := cid.Decode(data.Cid); err != nil || !got.Equals(want) {
+			return &data, &CidMismatchError{Expected: want, Actual: data.Cid}
+		}
+	}
+
+	return &data, nil
+}
+
+// errUploadAbandoned stops the reading of content that was not uploaded in
+// full.
+var errUploadAbandoned = errors.New("upload abandoned")
+
+// uploadBody is the body of an upload, written by a goroutine that reads the
+// content.
+type uploadBody struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// stop closes the body and waits for the goroutine writing it to exit.
+func (b *uploadBody) stop() {
+	b.CloseWithError(errUploadAbandoned)
+	<-b.done
+}
+
+// multipartBody returns a body that streams the content read from src as a
+// multipart form using the supplied boundary.
+func (r *ContentAddReq) multipartBody(src io.Reader, boundary string) *uploadBody {
 	pr, pw := io.Pipe()
 	mw := multipart.NewWriter(pw)
+	body := &uploadBody{PipeReader: pr, done: make(chan struct{})}
 
 	go func() {
+		defer close(body.done)
 		var outerr error
 		defer func() {
 			if outerr != nil {
@@ -92,29 +371,57 @@ func (r *ContentAddReq) Send() (*AddedContent, error) {
 			}
 		}()
 
+		if err := mw.SetBoundary(boundary); err != nil {
+			outerr = err
+			return
+		}
+
 		part, err := mw.CreateFormFile("data", r.name)
 		if err != nil {
 			outerr = err
 			return
 		}
 
-		_, err = io.Copy(part, r.r)
+		_, err = io.Copy(part, src)
 		if err != nil {
 			outerr = err
 			return
 		}
-		mw.Close()
+		outerr = mw.Close()
 	}()
 
-	r.req.headers["Content-Type"] = mw.FormDataContentType()
+	return body
+}
+
+// Viewer prepares a request for information about the authenticated user
+// and their account settings.
+func (c *AuthedClient) Viewer() *ViewerReq {
+	return &ViewerReq{
+		client: c,
+		req:    c.newReq("/viewer"),
+	}
+}
+
+type ViewerReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ViewerReq) Context(ctx context.Context) *ViewerReq {
+	r.req.ctx = ctx
+	return r
+}
 
-	res, cleanup, err := r.req.post(pr)
+// Send sends the prepared request and returns information about the user.
+func (r *ViewerReq) Send() (*Viewer, error) {
+	res, cleanup, err := r.req.get()
 	defer cleanup()
 	if err != nil {
 		return nil, err
 	}
 
-	var data AddedContent
+	var data Viewer
 	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
 		return nil, newResponseError(err, res)
 	}
@@ -181,3 +488,89 @@ func (r *ContentAddFromIpfsReq) Send() (*IpfsPinStatus, error) {
 
 	return &data, nil
 }
+
+// ContentAddCar prepares a request to upload a CAR file read from r. Estuary
+// requires the CAR to have a single root.
+func (c *AuthedClient) ContentAddCar(r io.Reader) *ContentAddCarReq {
+	cr := &ContentAddCarReq{
+		client: c,
+		req:    c.newReq("/content/add-car"),
+		r:      r,
+	}
+	cr.req.class = EndpointUpload
+	return cr
+}
+
+type ContentAddCarReq struct {
+	req
+	client   *AuthedClient
+	r        io.Reader
+	validate bool
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentAddCarReq) Context(ctx context.Context) *ContentAddCarReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Filename sets the name to be associated with the content. By default
+// Estuary uses the root cid of the CAR.
+func (r *ContentAddCarReq) Filename(v string) *ContentAddCarReq {
+	r.req.par.Set("filename", v)
+	return r
+}
+
+// Collection sets the uuid of a collection that the content should be added to.
+func (r *ContentAddCarReq) Collection(v string) *ContentAddCarReq {
+	r.req.par.Set("coluuid", v)
+	return r
+}
+
+// Validate enables local validation of the CAR before it is uploaded. The
+// header is parsed to check that it has a single valid root cid and the cid
+// reported by Estuary is checked against that root, with a *CidMismatchError
+// returned if they differ.
+func (r *ContentAddCarReq) Validate() *ContentAddCarReq {
+	r.validate = true
+	return r
+}
+
+// Send sends the prepared request and returns information about the added content.
+func (r *ContentAddCarReq) Send() (*AddedContent, error) {
+	body := r.r
+	var root cid.Cid
+	if r.validate {
+		h, br, err := peekCarHeader(r.r)
+		if err != nil {
+			return nil, err
+		}
+		if len(h.Roots) != 1 {
+			return nil, fmt.Errorf("car must have a single root, found %d", len(h.Roots))
+		}
+		root = h.Roots[0]
+		body = br
+	}
+
+	r.req.headers["Content-Type"] = "application/vnd.ipld.car"
+
+	res, cleanup, err := r.req.post(body)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data AddedContent
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	if r.validate {
+		got, err := cid.Decode(data.Cid)
+		if err != nil || !got.Equals(root) {
+			return &data, &CidMismatchError{Expected: root, Actual: data.Cid}
+		}
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,85 @@
+package creek
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// unixHost is the placeholder host used in request URLs when connecting to
+// the API over a unix socket.
+const unixHost = "unix"
+
+// parseBaseURL parses the address of an Estuary API. The address may be a
+// bare host with an optional port, such as api.estuary.tech, which implies
+// the https scheme. Alternatively it may be a full URL using the http or https
+// scheme with an optional path prefix, such as http://localhost:3004/estuary,
+// or a unix socket URL such as unix:///var/run/estuary.sock.
+func parseBaseURL(addr string) (*url.URL, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("invalid base url: address must not be empty")
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "https://" + addr
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid base url %q: must not contain a query or fragment", addr)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid base url %q: missing host", addr)
+		}
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			return nil, fmt.Errorf("invalid base url %q: must be of the form unix:///path/to/socket", addr)
+		}
+	default:
+		return nil, fmt.Errorf("invalid base url %q: unsupported scheme %q", addr, u.Scheme)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return u, nil
+}
+
+// resolveBaseURL returns the base URL used to build request URLs and the HTTP
+// client that should be used to send them. Unix socket addresses are
+// rewritten to plain http URLs and the client is replaced with one that dials
+// the socket.
+func resolveBaseURL(hc *http.Client, u *url.URL) (*http.Client, *url.URL) {
+	if u.Scheme != "unix" {
+		return hc, u
+	}
+	return unixSocketClient(hc, u.Path), &url.URL{Scheme: "http", Host: unixHost}
+}
+
+// unixSocketClient returns a copy of the HTTP client whose connections are
+// made to the unix socket at path.
+func unixSocketClient(hc *http.Client, path string) *http.Client {
+	var tr *http.Transport
+	if t, ok := hc.Transport.(*http.Transport); ok {
+		tr = t.Clone()
+	} else {
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	tr.Proxy = nil
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+
+	nc := *hc
+	nc.Transport = tr
+	return &nc
+}
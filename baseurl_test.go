@@ -0,0 +1,95 @@
+package creek
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseBaseURL(t *testing.T) {
+	testCases := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{addr: "api.estuary.tech", want: "https://api.estuary.tech"},
+		{addr: "localhost:3004", want: "https://localhost:3004"},
+		{addr: "http://localhost:3004", want: "http://localhost:3004"},
+		{addr: "http://localhost:3004/estuary/", want: "http://localhost:3004/estuary"},
+		{addr: "https://example.com/a/b", want: "https://example.com/a/b"},
+		{addr: "unix:///var/run/estuary.sock", want: "unix:///var/run/estuary.sock"},
+		{addr: "", wantErr: true},
+		{addr: "http://localhost:3004/?key=value", wantErr: true},
+		{addr: "http://localhost:3004/#fragment", wantErr: true},
+		{addr: "ftp://example.com", wantErr: true},
+		{addr: "http:///estuary", wantErr: true},
+		{addr: "unix://", wantErr: true},
+		{addr: "unix://host/var/run/estuary.sock", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			u, err := parseBaseURL(tc.addr)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("got url %s, wanted error", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := u.String(); got != tc.want {
+				t.Errorf("got %s, wanted %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBaseURLPathPrefix(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	if _, err := New(srv.Client(), srv.URL+"/estuary/").Health().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "/estuary/health"; path != want {
+		t.Errorf("got path %s, wanted %s", path, want)
+	}
+}
+
+func TestBaseURLUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "estuary.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	var path string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	h, err := New(http.DefaultClient, "unix://"+sock).Health().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h.Status != "ok" {
+		t.Errorf("got status %q, wanted ok", h.Status)
+	}
+	if path != "/health" {
+		t.Errorf("got path %s, wanted /health", path)
+	}
+	if http.DefaultClient.Transport != nil {
+		t.Errorf("http.DefaultClient was modified")
+	}
+}
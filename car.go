@@ -0,0 +1,161 @@
+package creek
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+const (
+	// maxCarHeaderSize is the largest CAR header that will be read.
+	maxCarHeaderSize = 32 << 20
+
+	// maxCarBlockSize is the largest CAR block section that will be read.
+	maxCarBlockSize = 8 << 20
+)
+
+// CarHeader is the header of a version 1 CAR file.
+type CarHeader struct {
+	Roots   []cid.Cid `refmt:"roots"`
+	Version uint64    `refmt:"version"`
+}
+
+func init() {
+	cbor.RegisterCborType(CarHeader{})
+}
+
+// ReadCarHeader reads and parses the header of a version 1 CAR file from r.
+// Nothing beyond the header is read from r, so the blocks that follow it can
+// be read from r afterwards.
+func ReadCarHeader(r io.Reader) (*CarHeader, error) {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = &oneByteReader{Reader: r}
+	}
+
+	data, err := readCarSection(br, maxCarHeaderSize)
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("read car header: %w", io.ErrUnexpectedEOF)
+		}
+		return nil, fmt.Errorf("read car header: %w", err)
+	}
+
+	return decodeCarHeader(data)
+}
+
+func decodeCarHeader(data []byte) (*CarHeader, error) {
+	var h CarHeader
+	if err := cbor.DecodeInto(data, &h); err != nil {
+		return nil, fmt.Errorf("decode car header: %w", err)
+	}
+	if h.Version != 1 {
+		return nil, fmt.Errorf("unsupported car version: %d", h.Version)
+	}
+	if len(h.Roots) == 0 {
+		return nil, errors.New("car header has no roots")
+	}
+
+	return &h, nil
+}
+
+// peekCarHeader reads the header of a CAR file from r and returns it along
+// with a reader that yields the complete CAR, including the header.
+func peekCarHeader(r io.Reader) (*CarHeader, io.Reader, error) {
+	var buf bytes.Buffer
+	h, err := ReadCarHeader(io.TeeReader(r, &buf))
+	if err != nil {
+		return nil, nil, err
+	}
+	return h, io.MultiReader(&buf, r), nil
+}
+
+// writeCarHeader writes a version 1 CAR header with the supplied roots.
+func writeCarHeader(w io.Writer, roots ...cid.Cid) error {
+	data, err := cbor.DumpObject(&CarHeader{Roots: roots, Version: 1})
+	if err != nil {
+		return err
+	}
+	return writeCarSection(w, data)
+}
+
+// writeCarBlock writes a block to a CAR file.
+func writeCarBlock(w io.Writer, c cid.Cid, data []byte) error {
+	return writeCarSection(w, append(c.Bytes(), data...))
+}
+
+// verifyCarBlock parses a block section of a CAR file and checks that the
+// block's data matches its cid.
+func verifyCarBlock(section []byte) (cid.Cid, error) {
+	n, c, err := cid.CidFromBytes(section)
+	if err != nil {
+		return cid.Undef, fmt.Errorf("read block cid: %w", err)
+	}
+	actual, err := c.Prefix().Sum(section[n:])
+	if err != nil {
+		return cid.Undef, fmt.Errorf("hash block %s: %w", c, err)
+	}
+	if !actual.Equals(c) {
+		return cid.Undef, &CidMismatchError{Expected: c, Actual: actual.String()}
+	}
+	return c, nil
+}
+
+// byteReader is the reader used to read sections of a CAR file.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// oneByteReader adds a ReadByte method to a reader without reading ahead.
+type oneByteReader struct {
+	io.Reader
+	buf [1]byte
+}
+
+func (r *oneByteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.Reader, r.buf[:]); err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
+
+// readCarSection reads a varint length prefixed section of a CAR file. It
+// returns io.EOF only if there are no more sections.
+func readCarSection(br byteReader, max uint64) ([]byte, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read section length: %w", err)
+	}
+	if l == 0 || l > max {
+		return nil, fmt.Errorf("invalid section length: %d", l)
+	}
+
+	data := make([]byte, l)
+	if _, err := io.ReadFull(br, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeCarSection writes a varint length prefixed section of a CAR file.
+func writeCarSection(w io.Writer, data []byte) error {
+	var lenbuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenbuf[:], uint64(len(data)))
+	if _, err := w.Write(lenbuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
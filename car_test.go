@@ -0,0 +1,152 @@
+package creek_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+var testFS = fstest.MapFS{
+	"a.txt":       {Data: []byte("alpha")},
+	"dir/b.txt":   {Data: bytes.Repeat([]byte("bravo"), 100000)},
+	"dir/c/d.txt": {Data: []byte("delta")},
+}
+
+// testDirCar uploads testFS to the server and fetches it back as a CAR.
+func testDirCar(t *testing.T, s *creektest.Server) (*creek.DirUpload, []byte) {
+	t.Helper()
+	up, err := s.AuthedClient("token").ContentAddDir(testFS).Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc, err := s.Client().Retrieve(up.Root).Car().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	car, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return up, car
+}
+
+func TestContentAddCar(t *testing.T) {
+	src := creektest.NewServer()
+	defer src.Close()
+	up, car := testDirCar(t, src)
+
+	s := creektest.NewServer()
+	defer s.Close()
+	added, err := s.AuthedClient("token").ContentAddCar(bytes.NewReader(car)).Filename("dir").Validate().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added.Cid != up.Root.String() {
+		t.Errorf("got cid %s, wanted %s", added.Cid, up.Root)
+	}
+	contents := s.Contents()
+	if len(contents) != 1 || contents[0].Name != "dir" {
+		t.Errorf("got contents %+v, wanted one named dir", contents)
+	}
+}
+
+func TestContentAddCarInvalid(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	header, err := cbor.DumpObject(&creek.CarHeader{
+		Roots:   []cid.Cid{testCid(t, 1), testCid(t, 2)},
+		Version: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	multiRoot := make([]byte, binary.MaxVarintLen64)
+	multiRoot = append(multiRoot[:binary.PutUvarint(multiRoot, uint64(len(header)))], header...)
+
+	testCases := []struct {
+		name string
+		car  []byte
+	}{
+		{name: "multiple roots", car: multiRoot},
+		{name: "garbage", car: []byte("not a car file")},
+		{name: "empty", car: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ac.ContentAddCar(bytes.NewReader(tc.car)).Validate().Send(); err == nil {
+				t.Errorf("got no error, wanted invalid car to be rejected")
+			}
+		})
+	}
+	if n := len(s.Contents()); n != 0 {
+		t.Errorf("got %d contents, wanted invalid cars not to be uploaded", n)
+	}
+}
+
+func TestContentAddCarMismatch(t *testing.T) {
+	src := creektest.NewServer()
+	defer src.Close()
+	up, car := testDirCar(t, src)
+
+	s := creektest.NewServer()
+	defer s.Close()
+	other := testCid(t, 1)
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/content/add-car" {
+			return false
+		}
+		io.Copy(ioutil.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"cid":"` + other.String() + `","estuaryId":1}`))
+		return true
+	})
+
+	_, err := s.AuthedClient("token").ContentAddCar(bytes.NewReader(car)).Validate().Send()
+	var cme *creek.CidMismatchError
+	if !errors.As(err, &cme) {
+		t.Fatalf("got error %v, wanted *CidMismatchError", err)
+	}
+	if !cme.Expected.Equals(up.Root) || cme.Actual != other.String() {
+		t.Errorf("got mismatch %v, wanted expected %s and actual %s", cme, up.Root, other)
+	}
+}
+
+func TestReadCarHeader(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	up, car := testDirCar(t, s)
+
+	// Hide any methods of the reader other than Read.
+	r := struct{ io.Reader }{bytes.NewReader(car)}
+	h, err := creek.ReadCarHeader(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.Roots) != 1 || !h.Roots[0].Equals(up.Root) {
+		t.Errorf("got roots %v, wanted %s", h.Roots, up.Root)
+	}
+
+	// The blocks following the header can still be read from r.
+	l, n := binary.Uvarint(car)
+	rest, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := car[n+int(l):]; !bytes.Equal(rest, want) {
+		t.Errorf("got %d bytes after the header, wanted %d", len(rest), len(want))
+	}
+}
@@ -23,19 +23,29 @@ const (
 // Client is the base client used for interacting with services that do not
 // require authentication.
 type Client struct {
-	// never modified once they have been set
-	hc   *http.Client
-	addr string
-	ua   string
+	config
+	tokens TokenSource // used by Authed, never modified once set
 }
 
 // New creates a new client that will use the supplied HTTP client and connect
-// via the specified API host address.
+// via the specified API address. The address may be a bare host name such as
+// DefaultAddr, which is accessed using https, or a full base URL including a
+// scheme, port and path prefix such as http://localhost:3004/estuary. A unix
+// socket may be specified using a URL of the form unix:///path/to/socket. If
+// the address is not valid then every request made using the client will
+// fail with an error describing the problem.
 func New(client *http.Client, addr string) *Client {
 	c := &Client{
-		hc:   client,
-		addr: addr,
+		config: config{
+			hc: client,
+		},
+	}
+	base, err := parseBaseURL(addr)
+	if err != nil {
+		c.err = err
+		return c
 	}
+	c.hc, c.base = resolveBaseURL(client, base)
 	return c
 }
 
@@ -46,31 +56,59 @@ func NewDefault() *Client {
 }
 
 func (c *Client) newReq(path string) req {
-	return req{
-		hc:   c.hc,
-		addr: c.addr,
-		path: path,
-		headers: headers{
-			"User-Agent": c.userAgent(),
-		},
-		par: params{},
+	return c.config.newReq(path, EndpointPublic)
+}
+
+// WithToken creates a Client with the supplied authentication token,
+// copying options set on the receiver.
+func (c *Client) WithToken(token string) *AuthedClient {
+	return c.WithTokenSource(StaticToken(token))
+}
+
+// WithTokenSource creates a Client that authenticates using tokens supplied
+// by ts, copying options set on the receiver.
+func (c *Client) WithTokenSource(ts TokenSource) *AuthedClient {
+	ac := &AuthedClient{
+		config: c.config,
+		tokens: ts,
 	}
+	ac.initServices()
+	return ac
 }
 
-func (c *Client) userAgent() string {
-	if c.ua == "" {
-		return DefaultUserAgent
+// Authed creates a Client using the authentication token supplied by the
+// WithAuthToken or WithTokenSource option, copying options set on the
+// receiver.
+func (c *Client) Authed() *AuthedClient {
+	if c.tokens == nil {
+		return c.WithToken("")
 	}
+	return c.WithTokenSource(c.tokens)
+}
 
-	return DefaultUserAgent + " " + c.ua
+// WithRetryPolicy creates a copy of the Client that retries failed requests
+// according to the supplied policy. A nil policy disables retries.
+func (c *Client) WithRetryPolicy(p *RetryPolicy) *Client {
+	nc := *c
+	nc.retry = p
+	return &nc
 }
 
-// WithToken creates a Client with the supplied authentication token,
-// copying options set on the receiver.
-func (c *Client) WithToken(token string) *AuthedClient {
-	ac := NewAuthedClient(c.hc, c.addr, token)
-	ac.ua = c.ua
-	return ac
+// WithRateLimiter creates a copy of the Client that waits on the supplied rate
+// limiter before sending each request. A nil limiter disables rate limiting.
+func (c *Client) WithRateLimiter(l *RateLimiter) *Client {
+	nc := *c
+	nc.limiter = l
+	return &nc
+}
+
+// WithPasswordHasher creates a copy of the Client that transforms passwords
+// sent by Login and Register using the supplied PasswordHasher. A nil hasher
+// sends passwords unchanged.
+func (c *Client) WithPasswordHasher(h PasswordHasher) *Client {
+	nc := *c
+	nc.hasher = h
+	return &nc
 }
 
 // PublicNodeInfo prepares a request for the health of the Estuary node.
@@ -20,7 +20,10 @@ func main() {
 	flag.Parse()
 	log.SetFlags(0)
 
-	c := creek.NewDefault()
+	c, err := creek.NewClient(creek.WithUserAgent("creekdemo"))
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
 
 	log.Printf("Fetching health of Estuary node")
 	h, err := c.Health().Send()
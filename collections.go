@@ -0,0 +1,287 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/ipfs/go-cid"
+)
+
+// CollectionServices provides access to collection related API services.
+type CollectionServices struct {
+	client *AuthedClient
+}
+
+func NewCollectionServices(a *AuthedClient) *CollectionServices {
+	return &CollectionServices{client: a}
+}
+
+// Create prepares a request to create a new collection.
+func (s *CollectionServices) Create(name string) *CollectionServicesCreateReq {
+	r := &CollectionServicesCreateReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/create"),
+	}
+	r.data.Name = name
+	return r
+}
+
+type CollectionServicesCreateReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesCreateReq) Context(ctx context.Context) *CollectionServicesCreateReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Description sets a description of the collection.
+func (r *CollectionServicesCreateReq) Description(v string) *CollectionServicesCreateReq {
+	r.data.Description = v
+	return r
+}
+
+// Send sends the prepared request and returns the new collection.
+func (r *CollectionServicesCreateReq) Send() (*Collection, error) {
+	res, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data Collection
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// List prepares a request for a list of the user's collections.
+func (s *CollectionServices) List() *CollectionServicesListReq {
+	return &CollectionServicesListReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/list"),
+	}
+}
+
+type CollectionServicesListReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesListReq) Context(ctx context.Context) *CollectionServicesListReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of collections.
+func (r *CollectionServicesListReq) Send() ([]Collection, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Collection
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// Content prepares a request for the content held in a collection.
+func (s *CollectionServices) Content(uuid string) *CollectionServicesContentReq {
+	return &CollectionServicesContentReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/content/" + url.PathEscape(uuid)),
+	}
+}
+
+type CollectionServicesContentReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesContentReq) Context(ctx context.Context) *CollectionServicesContentReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the content in the collection.
+func (r *CollectionServicesContentReq) Send() ([]Content, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Content
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// AddContent prepares a request to add existing content to a collection.
+// Content may be identified by its Estuary id or by cid.
+func (s *CollectionServices) AddContent(uuid string) *CollectionServicesAddContentReq {
+	r := &CollectionServicesAddContentReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/add-content"),
+	}
+	r.data.Collection = uuid
+	r.data.Contents = []uint{}
+	r.data.Cids = []string{}
+	return r
+}
+
+type CollectionServicesAddContentReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Contents   []uint   `json:"contents"`
+		Cids       []string `json:"cids"`
+		Collection string   `json:"coluuid"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesAddContentReq) Context(ctx context.Context) *CollectionServicesAddContentReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// IDs adds content with the supplied Estuary ids to the request.
+func (r *CollectionServicesAddContentReq) IDs(ids ...uint) *CollectionServicesAddContentReq {
+	r.data.Contents = append(r.data.Contents, ids...)
+	return r
+}
+
+// Cids adds content with the supplied cids to the request.
+func (r *CollectionServicesAddContentReq) Cids(cids ...cid.Cid) *CollectionServicesAddContentReq {
+	for _, c := range cids {
+		r.data.Cids = append(r.data.Cids, c.String())
+	}
+	return r
+}
+
+// Send sends the prepared request.
+func (r *CollectionServicesAddContentReq) Send() error {
+	_, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// Delete prepares a request to delete a collection. Content held in the
+// collection is not deleted.
+func (s *CollectionServices) Delete(uuid string) *CollectionServicesDeleteReq {
+	return &CollectionServicesDeleteReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/" + url.PathEscape(uuid)),
+	}
+}
+
+type CollectionServicesDeleteReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesDeleteReq) Context(ctx context.Context) *CollectionServicesDeleteReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *CollectionServicesDeleteReq) Send() error {
+	_, cleanup, err := r.req.delete()
+	defer cleanup()
+
+	return err
+}
+
+// FsList prepares a request to list the entries in a directory of a
+// collection's filesystem. Directories are slash separated paths starting at
+// the root of the collection, "/".
+func (s *CollectionServices) FsList(uuid string, dir string) *CollectionServicesFsListReq {
+	r := &CollectionServicesFsListReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/fs/list"),
+	}
+	r.req.par.Set("col", uuid)
+	r.req.par.Set("dir", dir)
+	return r
+}
+
+type CollectionServicesFsListReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesFsListReq) Context(ctx context.Context) *CollectionServicesFsListReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the entries in the directory.
+func (r *CollectionServicesFsListReq) Send() ([]CollectionFsEntry, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []CollectionFsEntry
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// FsAdd prepares a request to place content at a path in a collection's
+// filesystem. The content is identified by its Estuary id and path is a slash
+// separated path starting at the root of the collection.
+func (s *CollectionServices) FsAdd(uuid string, contentID uint, path string) *CollectionServicesFsAddReq {
+	r := &CollectionServicesFsAddReq{
+		client: s.client,
+		req:    s.client.newReq("/collections/fs/add"),
+	}
+	r.req.par.Set("col", uuid)
+	r.req.par.Set("content", formatID(contentID))
+	r.req.par.Set("path", path)
+	return r
+}
+
+type CollectionServicesFsAddReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *CollectionServicesFsAddReq) Context(ctx context.Context) *CollectionServicesFsAddReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *CollectionServicesFsAddReq) Send() error {
+	_, cleanup, err := r.req.post(nil)
+	defer cleanup()
+
+	return err
+}
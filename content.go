@@ -0,0 +1,259 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+)
+
+// ContentServices provides access to content related API services.
+type ContentServices struct {
+	client *AuthedClient
+}
+
+func NewContentServices(a *AuthedClient) *ContentServices {
+	return &ContentServices{client: a}
+}
+
+// List prepares a request for a list of the user's content.
+func (s *ContentServices) List() *ContentServicesListReq {
+	return &ContentServicesListReq{
+		client: s.client,
+		req:    s.client.newReq("/content/list"),
+	}
+}
+
+type ContentServicesListReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesListReq) Context(ctx context.Context) *ContentServicesListReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of content.
+func (r *ContentServicesListReq) Send() ([]Content, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Content
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// Get prepares a request for the content with the supplied Estuary id.
+func (s *ContentServices) Get(id uint) *ContentServicesGetReq {
+	return &ContentServicesGetReq{
+		client: s.client,
+		req:    s.client.newReq("/content/" + formatID(id)),
+	}
+}
+
+type ContentServicesGetReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesGetReq) Context(ctx context.Context) *ContentServicesGetReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the content.
+func (r *ContentServicesGetReq) Send() (*Content, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data Content
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Status prepares a request for the status of the content with the supplied
+// Estuary id, including the state of its deals.
+func (s *ContentServices) Status(id uint) *ContentServicesStatusReq {
+	return &ContentServicesStatusReq{
+		client: s.client,
+		req:    s.client.newReq("/content/status/" + formatID(id)),
+	}
+}
+
+type ContentServicesStatusReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesStatusReq) Context(ctx context.Context) *ContentServicesStatusReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the status of the content.
+func (r *ContentServicesStatusReq) Send() (*ContentStatus, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ContentStatus
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Failures prepares a request for the deal failures recorded for the content
+// with the supplied Estuary id.
+func (s *ContentServices) Failures(id uint) *ContentServicesFailuresReq {
+	return &ContentServicesFailuresReq{
+		client: s.client,
+		req:    s.client.newReq("/content/failures/" + formatID(id)),
+	}
+}
+
+type ContentServicesFailuresReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesFailuresReq) Context(ctx context.Context) *ContentServicesFailuresReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of deal failures.
+func (r *ContentServicesFailuresReq) Send() ([]MinerDealFailure, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []MinerDealFailure
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// Delete prepares a request to unpin and delete the content with the
+// supplied Estuary id. Deals already made for the content are not affected.
+func (s *ContentServices) Delete(id uint) *ContentServicesDeleteReq {
+	return &ContentServicesDeleteReq{
+		client: s.client,
+		req:    s.client.newReq("/content/" + formatID(id)),
+	}
+}
+
+type ContentServicesDeleteReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesDeleteReq) Context(ctx context.Context) *ContentServicesDeleteReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *ContentServicesDeleteReq) Send() error {
+	_, cleanup, err := r.req.delete()
+	defer cleanup()
+
+	return err
+}
+
+// EnsureReplication prepares a request asking Estuary to check that the
+// content with the supplied cid has the required number of deals, making new
+// deals if it does not.
+func (s *ContentServices) EnsureReplication(c cid.Cid) *ContentServicesEnsureReplicationReq {
+	return &ContentServicesEnsureReplicationReq{
+		client: s.client,
+		req:    s.client.newReq("/content/ensure-replication/" + url.PathEscape(c.String())),
+	}
+}
+
+type ContentServicesEnsureReplicationReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesEnsureReplicationReq) Context(ctx context.Context) *ContentServicesEnsureReplicationReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *ContentServicesEnsureReplicationReq) Send() error {
+	_, cleanup, err := r.req.get()
+	defer cleanup()
+
+	return err
+}
+
+// StagingZones prepares a request for the user's staging zones. Estuary
+// gathers small content into a staging zone until the zone is large enough to
+// be aggregated and stored in a single deal.
+func (s *ContentServices) StagingZones() *ContentServicesStagingZonesReq {
+	return &ContentServicesStagingZonesReq{
+		client: s.client,
+		req:    s.client.newReq("/content/staging-zones"),
+	}
+}
+
+type ContentServicesStagingZonesReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentServicesStagingZonesReq) Context(ctx context.Context) *ContentServicesStagingZonesReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of staging zones.
+func (r *ContentServicesStagingZonesReq) Send() ([]StagingZone, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []StagingZone
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,979 @@
+// Package creektest provides an in-memory fake of the Estuary API for testing
+// code that uses the creek client.
+package creektest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+)
+
+// A Hook is called for every request received by the server before it is
+// handled. It returns true if it wrote a response, in which case the request
+// is not processed further.
+type Hook func(w http.ResponseWriter, r *http.Request) bool
+
+// Server is a fake Estuary API server that holds its state in memory. It
+// implements the health, public, login, register, viewer, content add, car
+// upload, pinning and gateway endpoints, and creation and revocation of
+// tokens. The gateway serves files uploaded with the content add endpoint
+// and, in CAR form, the DAGs of the CAR files uploaded with the car upload
+// endpoint.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	tokens      map[string]bool
+	users       map[string]account // keyed by username
+	nextToken   int
+	nextSalt    int
+	contents    []creek.Content
+	pins        map[string]*creek.IpfsPinStatus
+	files       map[string][]byte // file content keyed by cid
+	blocks      map[string][]byte // blocks of uploaded car files keyed by cid
+	nextID      uint
+	latency     time.Duration
+	failures    []*failure
+	hooks       []Hook
+	advancePins bool
+	endpoints   []string
+}
+
+// account is a registered user. Like Estuary, the server hashes the password
+// hash sent by the client again with a salt chosen for the account.
+type account struct {
+	salt     string
+	passHash string
+}
+
+type failure struct {
+	prefix    string
+	status    int
+	remaining int
+}
+
+// NewServer starts and returns a new fake Estuary server. The caller should
+// call Close when finished to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		tokens: make(map[string]bool),
+		users:  make(map[string]account),
+		pins:   make(map[string]*creek.IpfsPinStatus),
+		files:  make(map[string][]byte),
+		blocks: make(map[string][]byte),
+		nextID: 1,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a client configured to send requests to the server.
+func (s *Server) Client() *creek.Client {
+	return creek.New(s.Server.Client(), s.URL)
+}
+
+// AuthedClient registers the token with the server and returns an
+// authenticated client configured to send requests to the server using it.
+func (s *Server) AuthedClient(token string) *creek.AuthedClient {
+	s.AddToken(token)
+	return s.Client().WithToken(token)
+}
+
+// AddToken registers a token that will be accepted by authenticated endpoints.
+func (s *Server) AddToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = true
+}
+
+// RemoveToken revokes a token so it is no longer accepted.
+func (s *Server) RemoveToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// AddUser creates an account that accepts the supplied password sent
+// unchanged, as for an account created by the estuary setup command.
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = s.newAccount(password)
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Fail causes the next n requests whose path begins with prefix to fail with
+// the supplied HTTP status code.
+func (s *Server) Fail(prefix string, status int, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{prefix: prefix, status: status, remaining: n})
+}
+
+// AddHook adds a hook that is called for every request.
+func (s *Server) AddHook(h Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, h)
+}
+
+// AdvancePins controls whether pins progress through the queued, pinning and
+// pinned statuses each time their status is requested. By default pins stay
+// queued until changed with SetPinStatus.
+func (s *Server) AdvancePins(v bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advancePins = v
+}
+
+// SetUploadEndpoints sets the shuttle upload endpoints listed in the user
+// settings returned by the viewer endpoint.
+func (s *Server) SetUploadEndpoints(urls ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints = append([]string(nil), urls...)
+}
+
+// SetPinStatus sets the status of a pin. It returns false if no pin with the
+// request id exists.
+func (s *Server) SetPinStatus(requestId string, status creek.PinStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pins[requestId]
+	if !ok {
+		return false
+	}
+	p.Status = string(status)
+	return true
+}
+
+// SetPinCreated changes the creation time of the pin with the supplied
+// request id. It returns false if there is no such pin.
+func (s *Server) SetPinCreated(requestId string, t time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pins[requestId]
+	if !ok {
+		return false
+	}
+	p.Created = t.UTC().Round(0)
+	return true
+}
+
+// Pins returns a copy of every pin held by the server, newest first.
+func (s *Server) Pins() []creek.IpfsPinStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedPins()
+}
+
+// Contents returns a copy of every content item held by the server.
+func (s *Server) Contents() []creek.Content {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]creek.Content(nil), s.contents...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	latency := s.latency
+	hooks := append([]Hook(nil), s.hooks...)
+	status := s.takeFailure(r.URL.Path)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	for _, h := range hooks {
+		if h(w, r) {
+			return
+		}
+	}
+
+	if status != 0 {
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/health":
+		writeJSON(w, http.StatusOK, creek.Health{Status: "ok"})
+	case strings.HasPrefix(path, "/public/"):
+		s.servePublic(w, r)
+	case strings.HasPrefix(path, "/gw/ipfs/"):
+		s.serveGateway(w, r)
+	case path == "/login" && r.Method == http.MethodPost:
+		s.login(w, r, false)
+	case path == "/register" && r.Method == http.MethodPost:
+		s.login(w, r, true)
+	default:
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		s.serveAuthed(w, r)
+	}
+}
+
+func (s *Server) takeFailure(path string) int {
+	for i, f := range s.failures {
+		if strings.HasPrefix(path, f.prefix) {
+			f.remaining--
+			if f.remaining <= 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+			return f.status
+		}
+	}
+	return 0
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[strings.TrimPrefix(auth, "Bearer ")]
+}
+
+func (s *Server) servePublic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/public/stats":
+		s.mu.Lock()
+		stats := creek.PublicStats{TotalFilesStored: int64(len(s.contents))}
+		for _, c := range s.contents {
+			stats.TotalStorage += c.Size
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, stats)
+	case path == "/public/info":
+		writeJSON(w, http.StatusOK, creek.PublicNodeInfo{PrimaryAddress: "f01000"})
+	case strings.HasPrefix(path, "/public/by-cid/"):
+		s.mu.Lock()
+		infos := []creek.ContentInfo{}
+		for _, c := range s.contents {
+			if c.Cid == strings.TrimPrefix(path, "/public/by-cid/") {
+				infos = append(infos, creek.ContentInfo{Content: c, Deals: []creek.ContentDeal{}})
+			}
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, infos)
+	case strings.HasPrefix(path, "/public/miners/stats/"):
+		writeJSON(w, http.StatusOK, creek.MinerStats{Miner: strings.TrimPrefix(path, "/public/miners/stats/")})
+	case strings.HasPrefix(path, "/public/miners/deals/"):
+		writeJSON(w, http.StatusOK, []creek.MinerDeal{})
+	case strings.HasPrefix(path, "/public/miners/failures/"):
+		writeJSON(w, http.StatusOK, []creek.MinerDealFailure{})
+	case strings.HasPrefix(path, "/public/miners/storage/query/"):
+		writeJSON(w, http.StatusOK, creek.MinerStorageAsk{Miner: strings.TrimPrefix(path, "/public/miners/storage/query/")})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) serveAuthed(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/viewer" && r.Method == http.MethodGet:
+		s.viewer(w)
+	case path == "/user/api-keys" && r.Method == http.MethodPost:
+		s.createApiKey(w, r)
+	case strings.HasPrefix(path, "/user/api-keys/") && r.Method == http.MethodDelete:
+		s.RemoveToken(strings.TrimPrefix(path, "/user/api-keys/"))
+		w.WriteHeader(http.StatusOK)
+	case path == "/content/add" && r.Method == http.MethodPost:
+		s.contentAdd(w, r)
+	case path == "/content/add-car" && r.Method == http.MethodPost:
+		s.contentAddCar(w, r)
+	case path == "/content/add-ipfs" && r.Method == http.MethodPost:
+		s.contentAddIpfs(w, r)
+	case path == "/pinning/pins" && r.Method == http.MethodGet:
+		s.pinList(w, r)
+	case path == "/pinning/pins" && r.Method == http.MethodPost:
+		s.pinAdd(w, r)
+	case strings.HasPrefix(path, "/pinning/pins/"):
+		id := strings.TrimPrefix(path, "/pinning/pins/")
+		switch r.Method {
+		case http.MethodGet:
+			s.pinGet(w, id)
+		case http.MethodPost:
+			s.pinReplace(w, r, id)
+		case http.MethodDelete:
+			s.pinDelete(w, id)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// login registers or logs in a user, issuing a new token. The password hash
+// sent by the client is hashed with the account's salt before being stored
+// or compared, as Estuary does.
+func (s *Server) login(w http.ResponseWriter, r *http.Request, register bool) {
+	var body struct {
+		Username     string `json:"username"`
+		PasswordHash string `json:"passwordHash"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acct, exists := s.users[body.Username]
+	switch {
+	case register && exists:
+		writeError(w, http.StatusBadRequest, "username already taken")
+		return
+	case register:
+		s.users[body.Username] = s.newAccount(body.PasswordHash)
+	case !exists || hashPassword(body.PasswordHash, acct.salt) != acct.passHash:
+		writeError(w, http.StatusForbidden, "invalid username or password")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.newToken(30*24*time.Hour))
+}
+
+// newAccount creates an account with a new salt. The caller must hold s.mu.
+func (s *Server) newAccount(password string) account {
+	s.nextSalt++
+	salt := "salt" + strconv.Itoa(s.nextSalt)
+	return account{salt: salt, passHash: hashPassword(password, salt)}
+}
+
+// hashPassword hashes a password with an account's salt using Estuary's
+// scheme.
+func hashPassword(password, salt string) string {
+	sum := sha256.Sum256([]byte(password + "." + salt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) createApiKey(w http.ResponseWriter, r *http.Request) {
+	lifetime := 30 * 24 * time.Hour
+	if v := r.URL.Query().Get("expiry"); v != "" && v != "false" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		lifetime = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.newToken(lifetime))
+}
+
+// newToken issues a new token. The caller must hold s.mu.
+func (s *Server) newToken(lifetime time.Duration) creek.ApiKey {
+	s.nextToken++
+	token := "EST" + strconv.Itoa(s.nextToken) + "ARY"
+	s.tokens[token] = true
+	return creek.ApiKey{
+		Token:  token,
+		Expiry: time.Now().Add(lifetime).UTC().Round(0),
+	}
+}
+
+func (s *Server) viewer(w http.ResponseWriter) {
+	s.mu.Lock()
+	endpoints := append([]string{}, s.endpoints...)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, creek.Viewer{
+		ID:       1,
+		Username: "creektest",
+		Miners:   []string{},
+		Settings: creek.UserSettings{
+			Replication:          6,
+			DealDuration:         1555200,
+			FileStagingThreshold: 4 << 30,
+			UploadEndpoints:      endpoints,
+		},
+	})
+}
+
+func (s *Server) contentAdd(w http.ResponseWriter, r *http.Request) {
+	f, fh, err := r.FormFile("data")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := creek.ComputeCid(bytes.NewReader(data))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	content := s.addContent(c.String(), fh.Filename, int64(len(data)))
+	s.files[content.Cid] = data
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, creek.AddedContent{
+		Cid:       content.Cid,
+		EstuaryId: content.ID,
+		Providers: []string{},
+	})
+}
+
+func (s *Server) contentAddCar(w http.ResponseWriter, r *http.Request) {
+	br := bufio.NewReader(r.Body)
+	h, err := creek.ReadCarHeader(br)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(h.Roots) != 1 {
+		writeError(w, http.StatusBadRequest, "cannot handle uploading car files with multiple roots")
+		return
+	}
+	blocks := map[string][]byte{}
+	var n int64
+	for {
+		l, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		section := make([]byte, l)
+		if _, err := io.ReadFull(br, section); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		cl, c, err := cid.CidFromBytes(section)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		blocks[c.String()] = section[cl:]
+		n += int64(len(section) - cl)
+	}
+
+	name := r.URL.Query().Get("filename")
+	if name == "" {
+		name = h.Roots[0].String()
+	}
+
+	s.mu.Lock()
+	content := s.addContent(h.Roots[0].String(), name, n)
+	for c, data := range blocks {
+		s.blocks[c] = data
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, creek.AddedContent{
+		Cid:       content.Cid,
+		EstuaryId: content.ID,
+		Providers: []string{},
+	})
+}
+
+func (s *Server) contentAddIpfs(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Root       string   `json:"root"`
+		Name       string   `json:"name"`
+		Collection string   `json:"collection"`
+		Peers      []string `json:"peers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := cid.Decode(body.Root); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid root cid: "+err.Error())
+		return
+	}
+
+	meta := map[string]interface{}{}
+	if body.Collection != "" {
+		meta["collection"] = body.Collection
+	}
+
+	s.mu.Lock()
+	ps := s.addPin(creek.IpfsPin{Cid: body.Root, Name: body.Name, Origins: body.Peers, Meta: meta})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, ps)
+}
+
+func (s *Server) pinList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 10
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	var before, after time.Time
+	for name, t := range map[string]*time.Time{"before": &before, "after": &after} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid "+name+" timestamp")
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	var meta map[string]string
+	if v := q.Get("meta"); v != "" {
+		if err := json.Unmarshal([]byte(v), &meta); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid meta")
+			return
+		}
+	}
+
+	cids := splitList(q.Get("cid"))
+	statuses := splitList(q.Get("status"))
+	name := q.Get("name")
+	match := creek.TextMatch(q.Get("match"))
+	if match == "" {
+		match = creek.TextMatchExact
+	}
+
+	s.mu.Lock()
+	all := s.sortedPins()
+	s.mu.Unlock()
+
+	results := []creek.IpfsPinStatus{}
+	for _, p := range all {
+		switch {
+		case len(cids) > 0 && !contains(cids, p.Pin.Cid):
+		case len(statuses) > 0 && !contains(statuses, p.Status):
+		case name != "" && !matchText(match, p.Pin.Name, name):
+		case !before.IsZero() && !p.Created.Before(before):
+		case !after.IsZero() && !p.Created.After(after):
+		case !matchMeta(p.Pin.Meta, meta):
+		default:
+			results = append(results, p)
+		}
+	}
+
+	count := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	writeJSON(w, http.StatusOK, creek.PinList{Count: count, Results: results})
+}
+
+func (s *Server) pinAdd(w http.ResponseWriter, r *http.Request) {
+	var pin creek.IpfsPin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := cid.Decode(pin.Cid); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cid: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	ps := s.addPin(pin)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, ps)
+}
+
+func (s *Server) pinGet(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	p, ok := s.pins[id]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "pin not found")
+		return
+	}
+	ps := *p
+	if s.advancePins {
+		switch creek.PinStatus(p.Status) {
+		case creek.PinStatusQueued:
+			p.Status = string(creek.PinStatusPinning)
+		case creek.PinStatusPinning:
+			p.Status = string(creek.PinStatusPinned)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, ps)
+}
+
+func (s *Server) pinReplace(w http.ResponseWriter, r *http.Request, id string) {
+	var pin creek.IpfsPin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := cid.Decode(pin.Cid); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cid: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.pins[id]; !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "pin not found")
+		return
+	}
+	delete(s.pins, id)
+	ps := s.addPin(pin)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, ps)
+}
+
+func (s *Server) pinDelete(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.pins[id]
+	delete(s.pins, id)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "pin not found")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveGateway serves the content, or the CAR form of the DAG, at a path
+// beneath a cid.
+func (s *Server) serveGateway(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/gw/ipfs/"), "/"), "/")
+	c, err := cid.Decode(segments[0])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cid: "+err.Error())
+		return
+	}
+
+	wantCar := r.URL.Query().Get("format") == "car" || r.Header.Get("Accept") == "application/vnd.ipld.car"
+
+	s.mu.Lock()
+	var file []byte
+	isFile, isCar := false, false
+	path := s.resolve(c, segments[1:])
+	if path != nil {
+		target := path[len(path)-1].String()
+		file, isFile = s.files[target]
+		_, isCar = s.blocks[target]
+	}
+	var car bytes.Buffer
+	if wantCar && isCar {
+		err = s.writeCar(&car, path)
+	}
+	s.mu.Unlock()
+
+	switch {
+	case wantCar && isCar:
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		w.Write(car.Bytes())
+	case !wantCar && isFile:
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(file))
+	default:
+		writeError(w, http.StatusNotFound, "content not found")
+	}
+}
+
+// addContent records a new content item. The caller must hold s.mu.
+func (s *Server) addContent(c string, name string, size int64) creek.Content {
+	content := creek.Content{
+		ID:          s.nextID,
+		Cid:         c,
+		Name:        name,
+		Size:        size,
+		Active:      true,
+		Replication: 6,
+	}
+	s.nextID++
+	s.contents = append(s.contents, content)
+	return content
+}
+
+// addPin records a new queued pin and its content. The caller must hold s.mu.
+func (s *Server) addPin(pin creek.IpfsPin) creek.IpfsPinStatus {
+	if pin.Meta == nil {
+		pin.Meta = map[string]interface{}{}
+	}
+	content := s.addContent(pin.Cid, pin.Name, 0)
+	ps := &creek.IpfsPinStatus{
+		RequestId: strconv.FormatUint(uint64(content.ID), 10),
+		Status:    string(creek.PinStatusQueued),
+		Created:   time.Now().UTC().Round(0),
+		Pin:       pin,
+		Delegates: []string{},
+		Info:      map[string]interface{}{},
+	}
+	s.pins[ps.RequestId] = ps
+	return *ps
+}
+
+// sortedPins returns copies of all pins, newest first. The caller must hold s.mu.
+func (s *Server) sortedPins() []creek.IpfsPinStatus {
+	pins := make([]creek.IpfsPinStatus, 0, len(s.pins))
+	for _, p := range s.pins {
+		pins = append(pins, *p)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		if pins[i].Created.Equal(pins[j].Created) {
+			return pins[i].RequestId > pins[j].RequestId
+		}
+		return pins[i].Created.After(pins[j].Created)
+	})
+	return pins
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchText(m creek.TextMatch, v, pattern string) bool {
+	switch m {
+	case creek.TextMatchExactInsensitive:
+		return strings.EqualFold(v, pattern)
+	case creek.TextMatchPartial:
+		return strings.Contains(v, pattern)
+	case creek.TextMatchPartialInsensitive:
+		return strings.Contains(strings.ToLower(v), strings.ToLower(pattern))
+	default:
+		return v == pattern
+	}
+}
+
+func matchMeta(meta map[string]interface{}, want map[string]string) bool {
+	for k, v := range want {
+		if s, ok := meta[k].(string); !ok || s != v {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the cids of the blocks along a path of named links
+// beneath root, ending with the cid the path refers to. It returns nil if the
+// path cannot be resolved. The caller must hold s.mu.
+func (s *Server) resolve(root cid.Cid, segments []string) []cid.Cid {
+	cids := []cid.Cid{root}
+	for _, seg := range segments {
+		data, ok := s.blocks[cids[len(cids)-1].String()]
+		if !ok {
+			return nil
+		}
+		found := false
+		for _, l := range dagPbLinks(data) {
+			if l.name == seg {
+				cids = append(cids, l.cid)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return cids
+}
+
+// writeCar writes a CAR holding the blocks along the path, followed by the
+// blocks of the DAG beneath the last of them in depth first order, as a
+// gateway does. Blocks the server does not hold are omitted. The caller must
+// hold s.mu.
+func (s *Server) writeCar(w io.Writer, path []cid.Cid) error {
+	if err := writeCarHeader(w, &creek.CarHeader{Roots: []cid.Cid{path[0]}, Version: 1}); err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	write := func(c cid.Cid) error {
+		data, ok := s.blocks[c.String()]
+		if !ok || written[c.String()] {
+			return nil
+		}
+		written[c.String()] = true
+
+		section := append(c.Bytes(), data...)
+		var lenbuf [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(lenbuf[:], uint64(len(section)))
+		if _, err := w.Write(lenbuf[:n]); err != nil {
+			return err
+		}
+		_, err := w.Write(section)
+		return err
+	}
+	for _, c := range path[:len(path)-1] {
+		if err := write(c); err != nil {
+			return err
+		}
+	}
+
+	var walk func(c cid.Cid) error
+	walk = func(c cid.Cid) error {
+		if written[c.String()] {
+			return nil
+		}
+		if err := write(c); err != nil {
+			return err
+		}
+		data := s.blocks[c.String()]
+
+		if c.Type() != cid.DagProtobuf {
+			return nil
+		}
+		for _, l := range dagPbLinks(data) {
+			if err := walk(l.cid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(path[len(path)-1])
+}
+
+// dagPbLink is a link from a dag-pb node.
+type dagPbLink struct {
+	cid  cid.Cid
+	name string
+}
+
+// dagPbLinks returns the links of a dag-pb node, ignoring any that cannot be
+// decoded.
+func dagPbLinks(data []byte) []dagPbLink {
+	var links []dagPbLink
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 || key&7 != 2 {
+			break
+		}
+		data = data[n:]
+		l, n := binary.Uvarint(data)
+		if n <= 0 || l > uint64(len(data)-n) {
+			break
+		}
+		field := data[n : n+int(l)]
+		data = data[n+int(l):]
+		if key>>3 != 2 {
+			continue
+		}
+		var link dagPbLink
+		for len(field) > 0 {
+			key, n := binary.Uvarint(field)
+			if n <= 0 || key&7 != 2 {
+				break
+			}
+			field = field[n:]
+			l, n := binary.Uvarint(field)
+			if n <= 0 || l > uint64(len(field)-n) {
+				break
+			}
+			v := field[n : n+int(l)]
+			field = field[n+int(l):]
+			switch key >> 3 {
+			case 1:
+				link.cid, _ = cid.Cast(v)
+			case 2:
+				link.name = string(v)
+			}
+		}
+		if link.cid.Defined() {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// writeCarHeader writes the header of a version 1 CAR file.
+func writeCarHeader(w io.Writer, h *creek.CarHeader) error {
+	data, err := cbornode.DumpObject(h)
+	if err != nil {
+		return err
+	}
+	var lenbuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenbuf[:], uint64(len(data)))
+	if _, err := w.Write(lenbuf[:n]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, creek.Error{Error: msg})
+}
@@ -0,0 +1,30 @@
+package creektest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/iand/creek"
+)
+
+func TestServerFail(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	// Failures apply only to matching paths and only the requested number
+	// of times.
+	s.Fail("/public/", http.StatusNotFound, 2)
+	if _, err := c.Health().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.PublicStats().Send(); !errors.Is(err, creek.ErrNotFound) {
+			t.Errorf("request %d: got error %v, wanted ErrNotFound", i, err)
+		}
+	}
+	if _, err := c.PublicStats().Send(); err != nil {
+		t.Errorf("unexpected error after failures were used: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/filecoin-project/go-address"
+)
+
+// DealServices provides access to storage deal related API services.
+type DealServices struct {
+	client *AuthedClient
+}
+
+func NewDealServices(a *AuthedClient) *DealServices {
+	return &DealServices{client: a}
+}
+
+// Estimate prepares a request for an estimate of the cost of storing content
+// of the supplied size in bytes.
+func (s *DealServices) Estimate(size uint64) *DealServicesEstimateReq {
+	r := &DealServicesEstimateReq{
+		client: s.client,
+		req:    s.client.newReq("/deals/estimate"),
+	}
+	r.data.Size = size
+	return r
+}
+
+type DealServicesEstimateReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Size         uint64 `json:"size"`
+		Replication  int    `json:"replication"`
+		DurationBlks int64  `json:"durationBlks"`
+		Verified     bool   `json:"verified"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesEstimateReq) Context(ctx context.Context) *DealServicesEstimateReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Replication sets the number of deals to be made for the content.
+func (r *DealServicesEstimateReq) Replication(n int) *DealServicesEstimateReq {
+	r.data.Replication = n
+	return r
+}
+
+// Duration sets the duration of each deal as a number of epochs.
+func (r *DealServicesEstimateReq) Duration(epochs int64) *DealServicesEstimateReq {
+	r.data.DurationBlks = epochs
+	return r
+}
+
+// Verified sets whether the estimate should use verified deal prices.
+func (r *DealServicesEstimateReq) Verified(v bool) *DealServicesEstimateReq {
+	r.data.Verified = v
+	return r
+}
+
+// Send sends the prepared request and returns the estimate.
+func (r *DealServicesEstimateReq) Send() (*DealEstimate, error) {
+	res, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data DealEstimate
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Query prepares a request that asks a miner for its current storage ask.
+// Use QueryContent to check whether the ask accepts a deal for particular
+// content.
+func (s *DealServices) Query(addr address.Address) *DealServicesQueryReq {
+	return &DealServicesQueryReq{
+		client: s.client,
+		req:    s.client.newReq("/deals/query/" + url.PathEscape(addr.String())),
+	}
+}
+
+type DealServicesQueryReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesQueryReq) Context(ctx context.Context) *DealServicesQueryReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the miner's storage ask.
+func (r *DealServicesQueryReq) Send() (*MinerStorageAsk, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data MinerStorageAsk
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// QueryContent prepares a request that asks a miner for its current storage
+// ask and reports whether the ask accepts a deal for the content with the
+// supplied Estuary id. Estuary has no endpoint that queries an ask for
+// specific content, so the content is fetched and its size compared against
+// the piece size limits of the ask.
+func (s *DealServices) QueryContent(addr address.Address, contentID uint) *DealServicesQueryContentReq {
+	return &DealServicesQueryContentReq{
+		client:    s.client,
+		req:       s.client.newReq("/deals/query/" + url.PathEscape(addr.String())),
+		contentID: contentID,
+	}
+}
+
+type DealServicesQueryContentReq struct {
+	req
+	client    *AuthedClient
+	contentID uint
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesQueryContentReq) Context(ctx context.Context) *DealServicesQueryContentReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the miner's storage ask for
+// the content.
+func (r *DealServicesQueryContentReq) Send() (*ContentAsk, error) {
+	cr := r.client.Content.Get(r.contentID)
+	cr.req.ctx = r.req.ctx
+	content, err := cr.Send()
+	if err != nil {
+		return nil, err
+	}
+
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ContentAsk
+	if err := json.NewDecoder(res.Body).Decode(&data.MinerStorageAsk); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	data.ContentID = content.ID
+	data.PieceSize = PaddedPieceSize(uint64(content.Size))
+	data.Accepted = data.AcceptsPieceSize(data.PieceSize)
+	return &data, nil
+}
+
+// Make prepares a request to make a storage deal with a miner for the content
+// with the supplied Estuary id.
+func (s *DealServices) Make(addr address.Address, contentID uint) *DealServicesMakeReq {
+	r := &DealServicesMakeReq{
+		client: s.client,
+		req:    s.client.newReq("/deals/make/" + url.PathEscape(addr.String())),
+	}
+	r.data.Content = contentID
+	return r
+}
+
+type DealServicesMakeReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Content uint `json:"content"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesMakeReq) Context(ctx context.Context) *DealServicesMakeReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the Estuary id of the new deal.
+func (r *DealServicesMakeReq) Send() (*MadeDeal, error) {
+	res, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data MadeDeal
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Status prepares a request for the status of the deal with the supplied
+// Estuary id.
+func (s *DealServices) Status(dealID uint) *DealServicesStatusReq {
+	return &DealServicesStatusReq{
+		client: s.client,
+		req:    s.client.newReq("/deals/status/" + formatID(dealID)),
+	}
+}
+
+type DealServicesStatusReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesStatusReq) Context(ctx context.Context) *DealServicesStatusReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the status of the deal.
+func (r *DealServicesStatusReq) Send() (*DealStatus, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data DealStatus
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// TransferStatus prepares a request for the status of the data transfer for
+// the deal with the supplied Estuary id.
+func (s *DealServices) TransferStatus(dealID uint) *DealServicesTransferStatusReq {
+	return &DealServicesTransferStatusReq{
+		client: s.client,
+		req:    s.client.newReq("/deals/transfer/status/" + formatID(dealID)),
+	}
+}
+
+type DealServicesTransferStatusReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *DealServicesTransferStatusReq) Context(ctx context.Context) *DealServicesTransferStatusReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the status of the transfer.
+func (r *DealServicesTransferStatusReq) Send() (*TransferStatus, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data TransferStatus
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
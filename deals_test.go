@@ -0,0 +1,42 @@
+package creek
+
+import "testing"
+
+func TestPaddedPieceSize(t *testing.T) {
+	testCases := []struct {
+		size uint64
+		want uint64
+	}{
+		{size: 0, want: 128},
+		{size: 127, want: 128},
+		{size: 128, want: 256},
+		{size: 127 << 13, want: 1 << 20},
+		{size: 127<<13 + 1, want: 2 << 20},
+		{size: 1 << 30, want: 2 << 30},
+	}
+
+	for _, tc := range testCases {
+		if got := PaddedPieceSize(tc.size); got != tc.want {
+			t.Errorf("size %d: got %d, wanted %d", tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestAcceptsPieceSize(t *testing.T) {
+	ask := MinerStorageAsk{MinPieceSize: 256, MaxPieceSize: 1 << 20}
+	testCases := []struct {
+		size uint64
+		want bool
+	}{
+		{size: 128, want: false},
+		{size: 256, want: true},
+		{size: 1 << 20, want: true},
+		{size: 2 << 20, want: false},
+	}
+
+	for _, tc := range testCases {
+		if got := ask.AcceptsPieceSize(tc.size); got != tc.want {
+			t.Errorf("size %d: got %v, wanted %v", tc.size, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package creek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+// maxDirDepth limits the depth of directory trees that can be uploaded,
+// guarding against loops created by symbolic links.
+const maxDirDepth = 255
+
+// SymlinkPolicy determines how symbolic links are treated when uploading a
+// directory.
+type SymlinkPolicy int
+
+const (
+	// SymlinkSkip ignores symbolic links.
+	SymlinkSkip SymlinkPolicy = iota
+
+	// SymlinkFollow uploads the file or directory a symbolic link refers to
+	// in place of the link.
+	SymlinkFollow
+
+	// SymlinkError fails the upload if a symbolic link is found.
+	SymlinkError
+)
+
+// DirUpload is the result of uploading a directory.
+type DirUpload struct {
+	Root    cid.Cid            // cid of the root directory
+	Files   map[string]cid.Cid // cid of each file keyed by its slash separated path relative to the root
+	Content *AddedContent      // content added to Estuary
+}
+
+// ContentAddDir prepares a request to upload the directory tree held by fsys
+// as a UnixFS directory. The tree is packaged as a CAR file, built using the
+// same defaults as ComputeCid, and uploaded as described by ContentAddCar.
+// Very large directories are not sharded so each directory should hold no
+// more than a few thousand entries.
+func (c *AuthedClient) ContentAddDir(fsys fs.FS) *ContentAddDirReq {
+	return &ContentAddDirReq{
+		client: c,
+		fsys:   fsys,
+	}
+}
+
+// ContentAddDirPath prepares a request to upload the local directory at path.
+func (c *AuthedClient) ContentAddDirPath(path string) *ContentAddDirReq {
+	return c.ContentAddDir(os.DirFS(path))
+}
+
+type ContentAddDirReq struct {
+	client     *AuthedClient
+	ctx        context.Context
+	fsys       fs.FS
+	name       string
+	collection string
+	include    []string
+	exclude    []string
+	symlinks   SymlinkPolicy
+}
+
+// Context sets the context to be used during this request.
+func (r *ContentAddDirReq) Context(ctx context.Context) *ContentAddDirReq {
+	r.ctx = ctx
+	return r
+}
+
+// Name sets the name to be associated with the content. By default Estuary
+// uses the root cid of the directory.
+func (r *ContentAddDirReq) Name(v string) *ContentAddDirReq {
+	r.name = v
+	return r
+}
+
+// Collection sets the uuid of a collection that the content should be added to.
+func (r *ContentAddDirReq) Collection(v string) *ContentAddDirReq {
+	r.collection = v
+	return r
+}
+
+// Include restricts the upload to files matching at least one of the glob
+// patterns, using the syntax of path.Match. Patterns containing a slash are
+// matched against the path of the file relative to the root, others against
+// the file's name. Directories left empty by the filter are omitted.
+func (r *ContentAddDirReq) Include(patterns ...string) *ContentAddDirReq {
+	r.include = append(r.include, patterns...)
+	return r
+}
+
+// Exclude omits files and directories matching any of the glob patterns,
+// which are interpreted as for Include. Exclusions take precedence over
+// inclusions.
+func (r *ContentAddDirReq) Exclude(patterns ...string) *ContentAddDirReq {
+	r.exclude = append(r.exclude, patterns...)
+	return r
+}
+
+// Symlinks sets how symbolic links are treated. The default is SymlinkSkip.
+func (r *ContentAddDirReq) Symlinks(p SymlinkPolicy) *ContentAddDirReq {
+	r.symlinks = p
+	return r
+}
+
+// Send builds and uploads the directory and returns the cids of the
+// directory and its files along with the added content.
+func (r *ContentAddDirReq) Send() (*DirUpload, error) {
+	for _, pat := range append(append([]string{}, r.include...), r.exclude...) {
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pat, err)
+		}
+	}
+
+	tree, err := r.scan(".", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first pass computes the root cid, which must be written in the
+	// CAR header before any blocks.
+	files := make(map[string]cid.Cid)
+	root, err := r.build(newDagBuilder(DagParams{}, nil), tree, files)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(r.writeCar(pw, tree, root.cid))
+	}()
+	defer pr.Close()
+
+	car := r.client.ContentAddCar(pr).Validate()
+	if r.ctx != nil {
+		car.Context(r.ctx)
+	}
+	if r.name != "" {
+		car.Filename(r.name)
+	}
+	if r.collection != "" {
+		car.Collection(r.collection)
+	}
+
+	added, err := car.Send()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DirUpload{
+		Root:    root.cid,
+		Files:   files,
+		Content: added,
+	}, nil
+}
+
+// writeCar writes the blocks of the directory tree to w as a CAR file. It
+// fails if the tree's content changed since its root cid was computed.
+func (r *ContentAddDirReq) writeCar(w io.Writer, tree *dirEntry, root cid.Cid) error {
+	if err := writeCarHeader(w, root); err != nil {
+		return err
+	}
+
+	written := cid.NewSet()
+	b := newDagBuilder(DagParams{}, func(c cid.Cid, data []byte) error {
+		if !written.Visit(c) {
+			return nil
+		}
+		return writeCarBlock(w, c, data)
+	})
+
+	n, err := r.build(b, tree, nil)
+	if err != nil {
+		return err
+	}
+	if !n.cid.Equals(root) {
+		return errors.New("directory content changed during upload")
+	}
+	return nil
+}
+
+// dirEntry is a file or directory to be uploaded.
+type dirEntry struct {
+	name     string
+	path     string // slash separated path relative to the root
+	dir      bool
+	children []*dirEntry
+}
+
+// scan reads the directory at p and its descendants, applying the filters and
+// symlink policy.
+func (r *ContentAddDirReq) scan(p string, depth int) (*dirEntry, error) {
+	if depth > maxDirDepth {
+		return nil, fmt.Errorf("directory %s: too many levels of nested directories", p)
+	}
+
+	entries, err := fs.ReadDir(r.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	de := &dirEntry{name: path.Base(p), path: p, dir: true}
+	for _, e := range entries {
+		ep := path.Join(p, e.Name())
+		if r.excluded(ep) {
+			continue
+		}
+
+		isDir := e.IsDir()
+		mode := e.Type()
+		if mode&fs.ModeSymlink != 0 {
+			switch r.symlinks {
+			case SymlinkSkip:
+				continue
+			case SymlinkError:
+				return nil, fmt.Errorf("%s: symbolic links are not permitted", ep)
+			}
+			fi, err := fs.Stat(r.fsys, ep)
+			if err != nil {
+				return nil, err
+			}
+			isDir = fi.IsDir()
+			mode = fi.Mode().Type()
+		}
+
+		if isDir {
+			child, err := r.scan(ep, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if len(r.include) > 0 && len(child.children) == 0 {
+				continue
+			}
+			de.children = append(de.children, child)
+			continue
+		}
+
+		if !mode.IsRegular() || !r.included(ep) {
+			continue
+		}
+		de.children = append(de.children, &dirEntry{name: e.Name(), path: ep})
+	}
+
+	return de, nil
+}
+
+// build builds the DAG for an entry, recording the cid of each file in files
+// if it is not nil.
+func (r *ContentAddDirReq) build(b *dagBuilder, e *dirEntry, files map[string]cid.Cid) (dagNode, error) {
+	if !e.dir {
+		f, err := r.fsys.Open(e.path)
+		if err != nil {
+			return dagNode{}, err
+		}
+		defer f.Close()
+
+		n, err := b.file(f)
+		if err != nil {
+			return dagNode{}, fmt.Errorf("%s: %w", e.path, err)
+		}
+		if files != nil {
+			files[e.path] = n.cid
+		}
+		return n, nil
+	}
+
+	links := make([]dagLink, 0, len(e.children))
+	for _, child := range e.children {
+		n, err := r.build(b, child, files)
+		if err != nil {
+			return dagNode{}, err
+		}
+		links = append(links, dagLink{name: child.name, node: n})
+	}
+	return b.directory(links)
+}
+
+func (r *ContentAddDirReq) included(p string) bool {
+	if len(r.include) == 0 {
+		return true
+	}
+	return matchAny(r.include, p)
+}
+
+func (r *ContentAddDirReq) excluded(p string) bool {
+	return matchAny(r.exclude, p)
+}
+
+// matchAny reports whether the slash separated path p matches any of the
+// patterns. Patterns without a slash are matched against the last element of
+// the path.
+func matchAny(patterns []string, p string) bool {
+	for _, pat := range patterns {
+		target := p
+		if !strings.Contains(pat, "/") {
+			target = path.Base(p)
+		}
+		if ok, _ := path.Match(pat, target); ok {
+			return true
+		}
+	}
+	return false
+}
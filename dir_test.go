@@ -0,0 +1,201 @@
+package creek_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+func uploadedFiles(up *creek.DirUpload) []string {
+	var files []string
+	for p := range up.Files {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestContentAddDirFilters(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":           {Data: []byte("a")},
+		"b.go":            {Data: []byte("b")},
+		"docs/readme.md":  {Data: []byte("readme")},
+		"docs/notes.txt":  {Data: []byte("notes")},
+		"src/main.go":     {Data: []byte("main")},
+		"src/vendor/x.go": {Data: []byte("x")},
+		"tmp/scratch.tmp": {Data: []byte("scratch")},
+	}
+
+	testCases := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		wantFiles string
+		wantDirs  []string // directories expected in the upload
+		noDirs    []string // directories expected to be omitted
+	}{
+		{
+			name:      "no filters",
+			wantFiles: "[a.txt b.go docs/notes.txt docs/readme.md src/main.go src/vendor/x.go tmp/scratch.tmp]",
+			wantDirs:  []string{"docs", "src/vendor", "tmp"},
+		},
+		{
+			name:      "include by name",
+			include:   []string{"*.go"},
+			wantFiles: "[b.go src/main.go src/vendor/x.go]",
+			wantDirs:  []string{"src/vendor"},
+			noDirs:    []string{"docs", "tmp"},
+		},
+		{
+			name:      "include by path",
+			include:   []string{"src/*.go"},
+			wantFiles: "[src/main.go]",
+			wantDirs:  []string{"src"},
+			noDirs:    []string{"src/vendor", "docs"},
+		},
+		{
+			name:      "exclude directory by name",
+			exclude:   []string{"vendor"},
+			wantFiles: "[a.txt b.go docs/notes.txt docs/readme.md src/main.go tmp/scratch.tmp]",
+			noDirs:    []string{"src/vendor"},
+		},
+		{
+			name:      "exclude keeps empty directories",
+			exclude:   []string{"*.tmp"},
+			wantFiles: "[a.txt b.go docs/notes.txt docs/readme.md src/main.go src/vendor/x.go]",
+			wantDirs:  []string{"tmp"},
+		},
+		{
+			name:      "exclusion takes precedence",
+			include:   []string{"*.txt", "*.go"},
+			exclude:   []string{"notes.txt", "src/vendor"},
+			wantFiles: "[a.txt b.go src/main.go]",
+			wantDirs:  []string{"src"},
+			noDirs:    []string{"docs", "src/vendor"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := creektest.NewServer()
+			defer s.Close()
+
+			up, err := s.AuthedClient("token").ContentAddDir(fsys).Include(tc.include...).Exclude(tc.exclude...).Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprint(uploadedFiles(up)); got != tc.wantFiles {
+				t.Errorf("got files %s, wanted %s", got, tc.wantFiles)
+			}
+
+			for _, dir := range append(append([]string{}, tc.wantDirs...), tc.noDirs...) {
+				rc, err := s.Client().Retrieve(up.Root).Path(dir).Car().Send()
+				if err == nil {
+					rc.Close()
+				}
+				want := contains(tc.wantDirs, dir)
+				if found := err == nil; found != want {
+					t.Errorf("directory %s: got present %v, wanted %v (err: %v)", dir, found, want, err)
+				}
+			}
+		})
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestContentAddDirInvalidPattern(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	if _, err := s.AuthedClient("token").ContentAddDir(fsys).Include("[").Send(); err == nil {
+		t.Errorf("got no error for invalid pattern")
+	}
+}
+
+func TestContentAddDirSymlinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, data := range map[string]string{"file.txt": "file", "sub/inner.txt": "inner"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := os.Symlink("file.txt", filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+	if err := os.Symlink("sub", filepath.Join(root, "linkdir")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	t.Run("skip", func(t *testing.T) {
+		up, err := ac.ContentAddDirPath(root).Send()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := fmt.Sprint(uploadedFiles(up)), "[file.txt sub/inner.txt]"; got != want {
+			t.Errorf("got files %s, wanted %s", got, want)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		up, err := ac.ContentAddDirPath(root).Symlinks(creek.SymlinkFollow).Send()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, want := fmt.Sprint(uploadedFiles(up)), "[file.txt link.txt linkdir/inner.txt sub/inner.txt]"; got != want {
+			t.Errorf("got files %s, wanted %s", got, want)
+		}
+		if !up.Files["link.txt"].Equals(up.Files["file.txt"]) || !up.Files["linkdir/inner.txt"].Equals(up.Files["sub/inner.txt"]) {
+			t.Errorf("got different cids for links and their targets: %v", up.Files)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := ac.ContentAddDirPath(root).Symlinks(creek.SymlinkError).Send(); err == nil {
+			t.Errorf("got no error, wanted symbolic link to be rejected")
+		}
+	})
+
+	t.Run("follow loop", func(t *testing.T) {
+		loop := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(loop, "file.txt"), []byte("file"), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.Symlink(".", filepath.Join(loop, "self")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := ac.ContentAddDirPath(loop).Symlinks(creek.SymlinkFollow).Send(); err == nil {
+			t.Errorf("got no error, wanted self referencing link to be rejected")
+		}
+		if _, err := ac.ContentAddDirPath(loop).Send(); err != nil {
+			t.Errorf("unexpected error skipping self referencing link: %v", err)
+		}
+	})
+
+	if n := len(s.Contents()); n != 3 {
+		t.Errorf("got %d contents, wanted 3 successful uploads", n)
+	}
+}
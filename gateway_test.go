@@ -0,0 +1,138 @@
+package creek_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+	"github.com/ipfs/go-cid"
+)
+
+func TestRetrieve(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	added, err := s.AuthedClient("token").ContentAdd("digits", bytes.NewReader(data)).Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root, err := cid.Decode(added.Cid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		offset int64
+		length int64
+		want   []byte
+	}{
+		{name: "whole", want: data},
+		{name: "range", offset: 15, length: 20, want: data[15:35]},
+		{name: "remainder", offset: 9990, want: data[9990:]},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, err := s.Client().Retrieve(root).Range(tc.offset, tc.length).Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer rc.Close()
+			got, err := ioutil.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %d bytes, wanted %d bytes of the content", len(got), len(tc.want))
+			}
+		})
+	}
+}
+
+func TestRetrieveCar(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	up, car := testDirCar(t, s)
+
+	h, err := creek.ReadCarHeader(bytes.NewReader(car))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.Roots) != 1 || !h.Roots[0].Equals(up.Root) {
+		t.Errorf("got roots %v, wanted %s", h.Roots, up.Root)
+	}
+
+	// Only the blocks along the path and those of the file are sent for a
+	// path, which is smaller than the whole DAG.
+	rc, err := s.Client().Retrieve(up.Root).Path("dir/c/d.txt").Car().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	sub, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sub) >= len(car) {
+		t.Errorf("got %d bytes for path, wanted fewer than the %d bytes of the whole dag", len(sub), len(car))
+	}
+}
+
+func TestRetrieveCarCorrupt(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	up, car := testDirCar(t, s)
+
+	// The final block of the CAR is a leaf of one of the files.
+	corrupt := append([]byte(nil), car...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/gw/ipfs/") {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/vnd.ipld.car")
+		w.Write(corrupt)
+		return true
+	})
+
+	rc, err := s.Client().Retrieve(up.Root).Car().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	_, err = ioutil.ReadAll(rc)
+	var cme *creek.CidMismatchError
+	if !errors.As(err, &cme) {
+		t.Errorf("got error %v, wanted *CidMismatchError", err)
+	}
+}
+
+func TestRetrieveCarWrongRoot(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	_, car := testDirCar(t, s)
+
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/gw/ipfs/") {
+			return false
+		}
+		w.Write(car)
+		return true
+	})
+
+	rc, err := s.Client().Retrieve(testCid(t, 1)).Car().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	if _, err := ioutil.ReadAll(rc); err == nil {
+		t.Errorf("got no error for car with a different root")
+	}
+}
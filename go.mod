@@ -5,5 +5,7 @@ go 1.16
 require (
 	github.com/filecoin-project/go-address v0.0.6
 	github.com/ipfs/go-cid v0.1.0
+	github.com/ipfs/go-ipld-cbor v0.0.5
 	github.com/libp2p/go-libp2p-core v0.10.0
+	github.com/multiformats/go-multihash v0.0.15
 )
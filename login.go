@@ -0,0 +1,201 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// A PasswordHasher transforms a password into the value sent to Estuary's
+// login and registration endpoints as the password hash. Estuary hashes the
+// value it receives again with a salt it holds for each account, so a
+// hasher only needs to match the one used when the account was registered.
+// Clients without a hasher send passwords unchanged, matching accounts
+// created by the estuary setup command. Use WithPasswordHasher to log in to
+// accounts registered through a web client that hashes passwords before
+// sending them.
+type PasswordHasher func(password string) string
+
+// hashPassword returns the value sent as the password hash.
+func (c *config) hashPassword(password string) string {
+	if c.hasher == nil {
+		return password
+	}
+	return c.hasher(password)
+}
+
+// Login prepares a request to log in to Estuary with a username and password.
+// The password is transformed by the client's PasswordHasher, if any.
+func (c *Client) Login(username, password string) *LoginReq {
+	r := &LoginReq{
+		client:   c,
+		req:      c.newReq("/login"),
+		password: password,
+	}
+	r.data.Username = username
+	return r
+}
+
+type LoginReq struct {
+	req
+	client   *Client
+	password string
+	expiry   time.Time
+	data     struct {
+		Username     string `json:"username"`
+		PasswordHash string `json:"passwordHash"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *LoginReq) Context(ctx context.Context) *LoginReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// PasswordHash sets the value to be sent as the password hash instead of the
+// password supplied to Login transformed by the client's PasswordHasher.
+func (r *LoginReq) PasswordHash(v string) *LoginReq {
+	r.data.PasswordHash = v
+	return r
+}
+
+// Expiry returns the time that the token obtained by the most recent call to
+// Send expires.
+func (r *LoginReq) Expiry() time.Time {
+	return r.expiry
+}
+
+// Send sends the prepared request and returns a client authenticated with the
+// token issued by Estuary.
+func (r *LoginReq) Send() (*AuthedClient, error) {
+	if r.data.PasswordHash == "" {
+		r.data.PasswordHash = r.client.hashPassword(r.password)
+	}
+
+	res, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ApiKey
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	r.expiry = data.Expiry
+	return r.client.WithToken(data.Token), nil
+}
+
+// Register prepares a request to create a new Estuary account, returning a
+// client authenticated as the new user. The password is transformed by the
+// client's PasswordHasher, if any.
+func (c *Client) Register(username, password string) *RegisterReq {
+	r := &RegisterReq{
+		client:   c,
+		req:      c.newReq("/register"),
+		password: password,
+	}
+	r.data.Username = username
+	return r
+}
+
+type RegisterReq struct {
+	req
+	client   *Client
+	password string
+	expiry   time.Time
+	data     struct {
+		Username     string `json:"username"`
+		PasswordHash string `json:"passwordHash"`
+		InviteCode   string `json:"inviteCode"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *RegisterReq) Context(ctx context.Context) *RegisterReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// InviteCode sets the invite code required to register with nodes that
+// restrict registration.
+func (r *RegisterReq) InviteCode(v string) *RegisterReq {
+	r.data.InviteCode = v
+	return r
+}
+
+// PasswordHash sets the value to be sent as the password hash instead of the
+// password supplied to Register transformed by the client's PasswordHasher.
+func (r *RegisterReq) PasswordHash(v string) *RegisterReq {
+	r.data.PasswordHash = v
+	return r
+}
+
+// Expiry returns the time that the token obtained by the most recent call to
+// Send expires.
+func (r *RegisterReq) Expiry() time.Time {
+	return r.expiry
+}
+
+// Send sends the prepared request and returns a client authenticated with the
+// token issued for the new user.
+func (r *RegisterReq) Send() (*AuthedClient, error) {
+	if r.data.PasswordHash == "" {
+		r.data.PasswordHash = r.client.hashPassword(r.password)
+	}
+
+	res, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ApiKey
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	r.expiry = data.Expiry
+	return r.client.WithToken(data.Token), nil
+}
+
+// Logout prepares a request to revoke the token the client authenticates
+// with. The client cannot be used for authenticated requests afterwards.
+func (c *AuthedClient) Logout() *LogoutReq {
+	return &LogoutReq{
+		client: c,
+		req:    c.newReq("/user/api-keys/"),
+	}
+}
+
+type LogoutReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *LogoutReq) Context(ctx context.Context) *LogoutReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *LogoutReq) Send() error {
+	ctx := r.req.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	token, err := r.client.Token(ctx)
+	if err != nil {
+		return err
+	}
+	r.req.path = "/user/api-keys/" + url.PathEscape(token)
+
+	_, cleanup, err := r.req.delete()
+	defer cleanup()
+
+	return err
+}
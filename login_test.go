@@ -0,0 +1,89 @@
+package creek_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+// reverse is a PasswordHasher standing in for a web client's hashing.
+func reverse(password string) string {
+	b := []byte(password)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+
+func TestLogin(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	c := s.Client()
+	hc := c.WithPasswordHasher(reverse)
+
+	s.AddUser("admin", "secret")
+	if _, err := c.Register("alice", "secret").Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Register("alice", "other").Send(); err == nil {
+		t.Errorf("got no error registering a taken username")
+	}
+	if _, err := hc.Register("bob", "secret").Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		req      *creek.LoginReq
+		rejected bool
+	}{
+		{name: "setup account", req: c.Login("admin", "secret")},
+		{name: "setup account hashed", req: hc.Login("admin", "secret"), rejected: true},
+		{name: "password", req: c.Login("alice", "secret")},
+		{name: "wrong password", req: c.Login("alice", "guess"), rejected: true},
+		{name: "unknown user", req: c.Login("carol", "secret"), rejected: true},
+		{name: "hashed", req: hc.Login("bob", "secret")},
+		{name: "not hashed", req: c.Login("bob", "secret"), rejected: true},
+		{name: "hash", req: c.Login("bob", "").PasswordHash(reverse("secret"))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ac, err := tc.req.Send()
+			if tc.rejected {
+				if !errors.Is(err, creek.ErrUnauthorized) {
+					t.Errorf("got error %v, wanted ErrUnauthorized", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if time.Until(tc.req.Expiry()) <= 0 {
+				t.Errorf("got expiry %v, wanted a time in the future", tc.req.Expiry())
+			}
+			if _, err := ac.Viewer().Send(); err != nil {
+				t.Errorf("unexpected error using issued token: %v", err)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherOption(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+
+	c, err := creek.NewClient(creek.WithBaseURL(s.URL), creek.WithPasswordHasher(reverse))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Register("alice", "secret").Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Client().Login("alice", "").PasswordHash(reverse("secret")).Send(); err != nil {
+		t.Errorf("unexpected error logging in with the hashed password: %v", err)
+	}
+}
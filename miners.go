@@ -0,0 +1,237 @@
+package creek
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+
+	"github.com/filecoin-project/go-address"
+)
+
+// MinerServices provides access to API services that allow storage providers
+// to manage the miners they have claimed.
+type MinerServices struct {
+	client *AuthedClient
+}
+
+func NewMinerServices(a *AuthedClient) *MinerServices {
+	return &MinerServices{client: a}
+}
+
+// List prepares a request for a list of the miners claimed by the user.
+func (s *MinerServices) List() *MinerServicesListReq {
+	return &MinerServicesListReq{
+		client: s.client,
+		req:    s.client.newReq("/user/miners"),
+	}
+}
+
+type MinerServicesListReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesListReq) Context(ctx context.Context) *MinerServicesListReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of claimed miners.
+func (r *MinerServicesListReq) Send() ([]ClaimedMiner, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []ClaimedMiner
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// ClaimMessage prepares a request for the message that must be signed with
+// the miner's worker key to claim the miner.
+func (s *MinerServices) ClaimMessage(addr address.Address) *MinerServicesClaimMessageReq {
+	return &MinerServicesClaimMessageReq{
+		client: s.client,
+		req:    s.client.newReq("/miner/claim/" + url.PathEscape(addr.String())),
+	}
+}
+
+type MinerServicesClaimMessageReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesClaimMessageReq) Context(ctx context.Context) *MinerServicesClaimMessageReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the message to be signed.
+func (r *MinerServicesClaimMessageReq) Send() (*MinerClaimMessage, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data MinerClaimMessage
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Claim prepares a request to claim a miner for the user. The signature is
+// the serialized signature of the message returned by ClaimMessage, made with
+// the miner's worker key, as produced by the lotus wallet sign command.
+func (s *MinerServices) Claim(addr address.Address, signature []byte) *MinerServicesClaimReq {
+	r := &MinerServicesClaimReq{
+		client: s.client,
+		req:    s.client.newReq("/miner/claim"),
+	}
+	r.data.Miner = addr.String()
+	r.data.Claim = hex.EncodeToString(signature)
+	return r
+}
+
+type MinerServicesClaimReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Miner string `json:"miner"`
+		Claim string `json:"claim"`
+		Name  string `json:"name"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesClaimReq) Context(ctx context.Context) *MinerServicesClaimReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Name sets the display name of the miner.
+func (r *MinerServicesClaimReq) Name(v string) *MinerServicesClaimReq {
+	r.data.Name = v
+	return r
+}
+
+// Send sends the prepared request.
+func (r *MinerServicesClaimReq) Send() error {
+	_, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// SetInfo prepares a request to set the display information of a claimed
+// miner.
+func (s *MinerServices) SetInfo(addr address.Address) *MinerServicesSetInfoReq {
+	return &MinerServicesSetInfoReq{
+		client: s.client,
+		req:    s.client.newReq("/miner/set-info/" + url.PathEscape(addr.String())),
+	}
+}
+
+type MinerServicesSetInfoReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Name string `json:"name"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesSetInfoReq) Context(ctx context.Context) *MinerServicesSetInfoReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Name sets the display name of the miner.
+func (r *MinerServicesSetInfoReq) Name(v string) *MinerServicesSetInfoReq {
+	r.data.Name = v
+	return r
+}
+
+// Send sends the prepared request.
+func (r *MinerServicesSetInfoReq) Send() error {
+	_, cleanup, err := r.req.putJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// Suspend prepares a request to stop Estuary making deals with a claimed
+// miner.
+func (s *MinerServices) Suspend(addr address.Address) *MinerServicesSuspendReq {
+	return &MinerServicesSuspendReq{
+		client: s.client,
+		req:    s.client.newReq("/miner/suspend/" + url.PathEscape(addr.String())),
+	}
+}
+
+type MinerServicesSuspendReq struct {
+	req
+	client *AuthedClient
+	data   struct {
+		Reason string `json:"reason"`
+	}
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesSuspendReq) Context(ctx context.Context) *MinerServicesSuspendReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Reason sets the reason for the suspension, which is shown in the miner's
+// public stats.
+func (r *MinerServicesSuspendReq) Reason(v string) *MinerServicesSuspendReq {
+	r.data.Reason = v
+	return r
+}
+
+// Send sends the prepared request.
+func (r *MinerServicesSuspendReq) Send() error {
+	_, cleanup, err := r.req.postJSON(r.data)
+	defer cleanup()
+
+	return err
+}
+
+// Unsuspend prepares a request to allow Estuary to make deals with a
+// suspended miner again.
+func (s *MinerServices) Unsuspend(addr address.Address) *MinerServicesUnsuspendReq {
+	return &MinerServicesUnsuspendReq{
+		client: s.client,
+		req:    s.client.newReq("/miner/unsuspend/" + url.PathEscape(addr.String())),
+	}
+}
+
+type MinerServicesUnsuspendReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *MinerServicesUnsuspendReq) Context(ctx context.Context) *MinerServicesUnsuspendReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *MinerServicesUnsuspendReq) Send() error {
+	_, cleanup, err := r.req.put(nil)
+	defer cleanup()
+
+	return err
+}
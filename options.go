@@ -0,0 +1,207 @@
+package creek
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Logger is the interface used by clients to log diagnostic messages, such as
+// requests that are being retried. It is satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// config holds the settings shared by Client and AuthedClient.
+type config struct {
+	// never modified once they have been set
+	hc      *http.Client
+	base    *url.URL
+	err     error // set when the client was created with an invalid address
+	ua      string
+	timeout time.Duration
+	retry   *RetryPolicy
+	limiter *RateLimiter
+	logger  Logger
+	headers headers
+	hasher  PasswordHasher
+}
+
+func (c *config) userAgent() string {
+	if c.ua == "" {
+		return DefaultUserAgent
+	}
+
+	return DefaultUserAgent + " " + c.ua
+}
+
+func (c *config) newReq(path string, class EndpointClass) req {
+	hdrs := make(headers, len(c.headers)+1)
+	for k, v := range c.headers {
+		hdrs[k] = v
+	}
+	hdrs["User-Agent"] = c.userAgent()
+
+	return req{
+		hc:      c.hc,
+		base:    c.base,
+		err:     c.err,
+		path:    path,
+		headers: hdrs,
+		par:     params{},
+		timeout: c.timeout,
+		retry:   c.retry,
+		limiter: c.limiter,
+		logger:  c.logger,
+		class:   class,
+	}
+}
+
+// An Option configures a client created by NewClient.
+type Option func(*options) error
+
+type options struct {
+	config
+	addr   string
+	tokens TokenSource
+}
+
+// NewClient creates a new client configured by the supplied options. By
+// default the client uses http.DefaultClient and connects to DefaultAddr.
+func NewClient(opts ...Option) (*Client, error) {
+	o := options{
+		config: config{
+			hc: http.DefaultClient,
+		},
+		addr: DefaultAddr,
+	}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	base, err := parseBaseURL(o.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		config: o.config,
+		tokens: o.tokens,
+	}
+	c.hc, c.base = resolveBaseURL(o.hc, base)
+	return c, nil
+}
+
+// WithUserAgent sets a suffix that is appended to DefaultUserAgent to form
+// the User-Agent header sent with every request.
+func WithUserAgent(suffix string) Option {
+	return func(o *options) error {
+		o.ua = suffix
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) error {
+		if hc == nil {
+			return errors.New("http client must not be nil")
+		}
+		o.hc = hc
+		return nil
+	}
+}
+
+// WithBaseURL sets the address of the Estuary API. See New for the forms the
+// address may take.
+func WithBaseURL(addr string) Option {
+	return func(o *options) error {
+		if _, err := parseBaseURL(addr); err != nil {
+			return err
+		}
+		o.addr = addr
+		return nil
+	}
+}
+
+// WithAuthToken sets the authentication token used by the AuthedClient
+// returned from the client's Authed method.
+func WithAuthToken(token string) Option {
+	return func(o *options) error {
+		o.tokens = StaticToken(token)
+		return nil
+	}
+}
+
+// WithTokenSource sets the source of the authentication tokens used by the
+// AuthedClient returned from the client's Authed method.
+func WithTokenSource(ts TokenSource) Option {
+	return func(o *options) error {
+		if ts == nil {
+			return errors.New("token source must not be nil")
+		}
+		o.tokens = ts
+		return nil
+	}
+}
+
+// WithTimeout sets the maximum time allowed for each request, including any
+// retries. A timeout of zero means no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return errors.New("timeout must not be negative")
+		}
+		o.timeout = d
+		return nil
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests.
+func WithRetryPolicy(p *RetryPolicy) Option {
+	return func(o *options) error {
+		o.retry = p
+		return nil
+	}
+}
+
+// WithRateLimiter sets the rate limiter that is waited on before sending
+// each request.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(o *options) error {
+		o.limiter = l
+		return nil
+	}
+}
+
+// WithLogger sets the logger used for diagnostic messages.
+func WithLogger(l Logger) Option {
+	return func(o *options) error {
+		o.logger = l
+		return nil
+	}
+}
+
+// WithHeader sets a header that is sent with every request. Headers set by
+// the client library, such as User-Agent and Authorization, take precedence.
+func WithHeader(key, value string) Option {
+	return func(o *options) error {
+		if o.headers == nil {
+			o.headers = headers{}
+		}
+		o.headers[http.CanonicalHeaderKey(key)] = value
+		return nil
+	}
+}
+
+// WithPasswordHasher sets the PasswordHasher used to transform passwords sent
+// by Login and Register.
+func WithPasswordHasher(h PasswordHasher) Option {
+	return func(o *options) error {
+		o.hasher = h
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+package creek_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+// captureHeaders adds a hook to the server that records the headers of the
+// most recent request.
+func captureHeaders(s *creektest.Server) func() http.Header {
+	var mu sync.Mutex
+	var last http.Header
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		mu.Lock()
+		last = r.Header.Clone()
+		mu.Unlock()
+		return false
+	})
+	return func() http.Header {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, v...))
+}
+
+func (l *testLogger) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.msgs)
+}
+
+func TestNewClientHeaders(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	s.AddToken("token")
+	headers := captureHeaders(s)
+
+	c, err := creek.NewClient(
+		creek.WithBaseURL(s.URL),
+		creek.WithUserAgent("tests/1.0"),
+		creek.WithHeader("x-request-source", "options test"),
+		creek.WithHeader("User-Agent", "overridden"),
+		creek.WithHeader("Authorization", "Bearer overridden"),
+		creek.WithAuthToken("token"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.Health().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := headers()
+	if got, want := h.Get("User-Agent"), creek.DefaultUserAgent+" tests/1.0"; got != want {
+		t.Errorf("got user agent %q, wanted %q", got, want)
+	}
+	if got := h.Get("X-Request-Source"); got != "options test" {
+		t.Errorf("got header %q, wanted %q", got, "options test")
+	}
+
+	// The token supplied by the client takes precedence over the header.
+	if _, err := c.Authed().Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers().Get("Authorization"); got != "Bearer token" {
+		t.Errorf("got authorization %q, wanted the client's token", got)
+	}
+}
+
+func TestNewClientAuthed(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	s.AddToken("token")
+	headers := captureHeaders(s)
+
+	retry := creek.DefaultRetryPolicy()
+	retry.MinBackoff = time.Millisecond
+	retry.MaxBackoff = time.Millisecond
+	logger := &testLogger{}
+	limiter := creek.NewRateLimiter().SetLimit(creek.EndpointAuthed, 0.001, 2)
+
+	c, err := creek.NewClient(
+		creek.WithBaseURL(s.URL),
+		creek.WithHTTPClient(s.Server.Client()),
+		creek.WithTimeout(100*time.Millisecond),
+		creek.WithRetryPolicy(retry),
+		creek.WithRateLimiter(limiter),
+		creek.WithLogger(logger),
+		creek.WithHeader("X-Request-Source", "options test"),
+		creek.WithAuthToken("token"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clients := map[string]*creek.AuthedClient{
+		"WithToken": c.WithToken("token"),
+		"Authed":    c.Authed(),
+	}
+	for name, ac := range clients {
+		t.Run(name, func(t *testing.T) {
+			// The retried request uses both tokens of the rate limiter.
+			limiter.SetLimit(creek.EndpointAuthed, 0.001, 2)
+			before := logger.count()
+			s.Fail("/viewer", http.StatusServiceUnavailable, 1)
+			if _, err := ac.Viewer().Send(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if logger.count() == before {
+				t.Errorf("retry was not logged")
+			}
+			if got := headers().Get("X-Request-Source"); got != "options test" {
+				t.Errorf("got header %q, wanted %q", got, "options test")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := ac.Viewer().Context(ctx).Send(); err == nil {
+				t.Errorf("got no error, wanted the rate limiter to delay the request")
+			}
+
+			limiter.SetLimit(creek.EndpointAuthed, 0, 0)
+			s.SetLatency(time.Second)
+			defer s.SetLatency(0)
+			start := time.Now()
+			if _, err := ac.Viewer().Send(); err == nil {
+				t.Errorf("got no error, wanted the request to time out")
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("request took %v, wanted the client's timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestNewClientInvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name string
+		opt  creek.Option
+	}{
+		{name: "nil http client", opt: creek.WithHTTPClient(nil)},
+		{name: "base url scheme", opt: creek.WithBaseURL("ftp://example.com")},
+		{name: "base url query", opt: creek.WithBaseURL("http://example.com?a=b")},
+		{name: "negative timeout", opt: creek.WithTimeout(-time.Second)},
+		{name: "nil token source", opt: creek.WithTokenSource(nil)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := creek.NewClient(tc.opt); err == nil {
+				t.Errorf("got no error, wanted invalid option to be rejected")
+			}
+		})
+	}
+}
@@ -3,7 +3,11 @@ package creek
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -35,6 +39,68 @@ func (r *PinServicesListReq) Context(ctx context.Context) *PinServicesListReq {
 	return r
 }
 
+// Cid restricts the list to pins for any of the supplied cids. The Pinning
+// Service API allows at most 10 cids per request.
+func (r *PinServicesListReq) Cid(cids ...cid.Cid) *PinServicesListReq {
+	strs := make([]string, len(cids))
+	for i := range cids {
+		strs[i] = cids[i].String()
+	}
+	r.req.par.Set("cid", strings.Join(strs, ","))
+	return r
+}
+
+// Name restricts the list to pins with a matching name. By default the name
+// must match exactly, use Match to change the matching strategy.
+func (r *PinServicesListReq) Name(v string) *PinServicesListReq {
+	r.req.par.Set("name", v)
+	return r
+}
+
+// Match sets the text matching strategy used when filtering by name.
+func (r *PinServicesListReq) Match(v TextMatch) *PinServicesListReq {
+	r.req.par.Set("match", string(v))
+	return r
+}
+
+// Status restricts the list to pins with any of the supplied statuses.
+func (r *PinServicesListReq) Status(statuses ...PinStatus) *PinServicesListReq {
+	strs := make([]string, len(statuses))
+	for i := range statuses {
+		strs[i] = string(statuses[i])
+	}
+	r.req.par.Set("status", strings.Join(strs, ","))
+	return r
+}
+
+// Before restricts the list to pins created before the supplied time.
+func (r *PinServicesListReq) Before(t time.Time) *PinServicesListReq {
+	r.req.par.Set("before", t.UTC().Format(time.RFC3339Nano))
+	return r
+}
+
+// After restricts the list to pins created after the supplied time.
+func (r *PinServicesListReq) After(t time.Time) *PinServicesListReq {
+	r.req.par.Set("after", t.UTC().Format(time.RFC3339Nano))
+	return r
+}
+
+// Limit sets the maximum number of pins to return. The Pinning Service API
+// defaults to 10 and allows at most 1000.
+func (r *PinServicesListReq) Limit(n int) *PinServicesListReq {
+	r.req.par.Set("limit", strconv.Itoa(n))
+	return r
+}
+
+// Meta restricts the list to pins whose metadata contains all of the supplied
+// key/value pairs.
+func (r *PinServicesListReq) Meta(meta map[string]string) *PinServicesListReq {
+	// a map of strings always marshals successfully
+	encoded, _ := json.Marshal(meta)
+	r.req.par.Set("meta", string(encoded))
+	return r
+}
+
 // Send sends the prepared request and returns a list of pins.
 func (r *PinServicesListReq) Send() (*PinList, error) {
 	res, cleanup, err := r.req.get()
@@ -51,6 +117,168 @@ func (r *PinServicesListReq) Send() (*PinList, error) {
 	return &data, nil
 }
 
+// Iter returns an iterator that pages through every pin matching the request.
+// Each page is requested using the filters set on the request, with the before
+// filter advanced to the creation time of the oldest pin seen so far. When a
+// page holds only pins already seen at that time the page is requested again
+// with a larger limit, up to the maximum of 1000 allowed by the Pinning
+// Service API. Iteration stops with a *PinPageError if even that cannot reach
+// pins created before the time. The iterator works on a copy of the request,
+// which is left unchanged.
+func (r *PinServicesListReq) Iter() *PinIter {
+	cp := *r
+	cp.req = r.req.clone()
+	limit, err := strconv.Atoi(cp.req.par.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPinListLimit
+	}
+	return &PinIter{
+		req:   &cp,
+		limit: limit,
+		seen:  make(map[string]bool),
+	}
+}
+
+const (
+	// defaultPinListLimit is the number of pins the Pinning Service API
+	// returns when no limit is requested.
+	defaultPinListLimit = 10
+
+	// maxPinListLimit is the largest limit the Pinning Service API accepts.
+	maxPinListLimit = 1000
+)
+
+// PinPageError is returned by a PinIter when more pins share a creation time
+// than can be listed in a single page, so the pins created before that time
+// cannot be reached.
+type PinPageError struct {
+	Created time.Time // creation time shared by the pins
+	Limit   int       // limit of the largest page requested
+}
+
+func (e *PinPageError) Error() string {
+	return fmt.Sprintf("more than %d pins created at %s", e.Limit, e.Created.Format(time.RFC3339Nano))
+}
+
+// PinIter is an iterator over pins returned by a list request. Call Next to
+// advance the iterator and Pin to obtain the current pin. When Next returns
+// false, Err should be checked for any error that stopped the iteration.
+type PinIter struct {
+	req      *PinServicesListReq
+	page     []IpfsPinStatus
+	pin      *IpfsPinStatus
+	started  bool
+	done     bool
+	err      error
+	limit    int             // page size requested by the caller
+	boundary time.Time       // creation time of the oldest pin seen so far
+	seen     map[string]bool // request ids of pins created at the boundary time
+}
+
+// Next advances the iterator to the next pin, fetching a new page of results
+// when necessary. It returns false when there are no more pins or an error
+// occurred.
+func (it *PinIter) Next() bool {
+	for {
+		if it.err != nil {
+			return false
+		}
+		if len(it.page) > 0 {
+			it.pin = &it.page[0]
+			it.page = it.page[1:]
+			return true
+		}
+		if it.done {
+			it.pin = nil
+			return false
+		}
+		it.fetch()
+	}
+}
+
+// Pin returns the current pin.
+func (it *PinIter) Pin() *IpfsPinStatus {
+	return it.pin
+}
+
+// Err returns the first error encountered during iteration.
+func (it *PinIter) Err() error {
+	return it.err
+}
+
+func (it *PinIter) fetch() {
+	limit := it.limit
+	for {
+		if it.started {
+			// The before filter is exclusive so pins created at exactly the
+			// boundary time would be skipped. Request them again and discard
+			// any that have already been seen.
+			it.req.Before(it.boundary.Add(time.Nanosecond))
+		}
+		it.req.Limit(limit)
+
+		pl, err := it.req.Send()
+		if err != nil {
+			it.err = err
+			return
+		}
+		it.started = true
+
+		full := len(pl.Results) >= limit && (pl.Count == 0 || len(pl.Results) < pl.Count)
+		if !full {
+			it.done = true
+		}
+
+		var fresh []IpfsPinStatus
+		for _, p := range pl.Results {
+			if !it.seen[p.RequestId] {
+				fresh = append(fresh, p)
+			}
+		}
+
+		if len(fresh) > 0 {
+			it.accept(pl.Results, fresh)
+			return
+		}
+		if !full {
+			return
+		}
+
+		// Every pin in the page shares the boundary time and has been seen
+		// already. Ask for a larger page to reach past them.
+		if limit >= maxPinListLimit {
+			it.err = &PinPageError{Created: it.boundary, Limit: limit}
+			return
+		}
+		limit *= 2
+		if limit > maxPinListLimit {
+			limit = maxPinListLimit
+		}
+	}
+}
+
+// accept records the pins of a page as seen and queues the fresh ones.
+func (it *PinIter) accept(results, fresh []IpfsPinStatus) {
+	oldest := results[0].Created
+	for _, p := range results[1:] {
+		if p.Created.Before(oldest) {
+			oldest = p.Created
+		}
+	}
+
+	if !oldest.Equal(it.boundary) {
+		it.boundary = oldest
+		it.seen = make(map[string]bool)
+	}
+	for _, p := range results {
+		if p.Created.Equal(oldest) {
+			it.seen[p.RequestId] = true
+		}
+	}
+
+	it.page = fresh
+}
+
 // Add prepares a request to add a pin.
 func (s *PinServices) Add(ci cid.Cid) *PinServicesAddReq {
 	return &PinServicesAddReq{
@@ -0,0 +1,177 @@
+package creek_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// testCid returns a distinct cid for each value of n.
+func testCid(t *testing.T, n int) cid.Cid {
+	t.Helper()
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.SHA2_256, MhLength: -1}.Sum([]byte(fmt.Sprint(n)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func addPins(t *testing.T, ac *creek.AuthedClient, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < n; i++ {
+		ps, err := ac.Pins.Add(testCid(t, i)).Name(fmt.Sprintf("pin%d", i)).Send()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, ps.RequestId)
+	}
+	return ids
+}
+
+func TestPinIter(t *testing.T) {
+	base := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name    string
+		n       int
+		limit   int
+		created func(i int) time.Time
+		wantErr bool
+	}{
+		{
+			// Runs of pins with the same creation time cross page
+			// boundaries so the before filter alone cannot page through
+			// them.
+			name:  "runs within a page",
+			n:     23,
+			limit: 4,
+			created: func(i int) time.Time {
+				switch {
+				case i >= 5 && i < 8:
+					i = 5
+				case i >= 13 && i < 17:
+					i = 13
+				}
+				return base.Add(time.Duration(i) * time.Second)
+			},
+		},
+		{
+			name:  "run longer than a page",
+			n:     6,
+			limit: 2,
+			created: func(i int) time.Time {
+				if i > 0 {
+					i = 1
+				}
+				return base.Add(time.Duration(i) * time.Second)
+			},
+		},
+		{
+			name: "default limit",
+			n:    25,
+			created: func(i int) time.Time {
+				return base.Add(time.Duration(i/15) * time.Second)
+			},
+		},
+		{
+			name:  "run longer than the maximum page",
+			n:     1002,
+			limit: 100,
+			created: func(i int) time.Time {
+				if i > 0 {
+					i = 1
+				}
+				return base.Add(time.Duration(i) * time.Second)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := creektest.NewServer()
+			defer s.Close()
+			ac := s.AuthedClient("token")
+
+			ids := addPins(t, ac, tc.n)
+			for i, id := range ids {
+				s.SetPinCreated(id, tc.created(i))
+			}
+
+			req := ac.Pins.List()
+			if tc.limit > 0 {
+				req.Limit(tc.limit)
+			}
+			it := req.Iter()
+			seen := map[string]int{}
+			for it.Next() {
+				seen[it.Pin().RequestId]++
+			}
+
+			if tc.wantErr {
+				var ppe *creek.PinPageError
+				if !errors.As(it.Err(), &ppe) {
+					t.Fatalf("got error %v, wanted *PinPageError", it.Err())
+				}
+				if !ppe.Created.Equal(tc.created(1)) || ppe.Limit != 1000 {
+					t.Errorf("got error for %d pins at %v, wanted 1000 at %v", ppe.Limit, ppe.Created, tc.created(1))
+				}
+				return
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, id := range ids {
+				if seen[id] != 1 {
+					t.Errorf("pin %s seen %d times, wanted once", id, seen[id])
+				}
+			}
+			if len(seen) != len(ids) {
+				t.Errorf("got %d pins, wanted %d", len(seen), len(ids))
+			}
+
+			// Iterating must not change the request.
+			pl, err := req.Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pl.Count != len(ids) {
+				t.Errorf("got count %d after iterating, wanted %d", pl.Count, len(ids))
+			}
+		})
+	}
+}
+
+func TestPinIterFiltered(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	ids := addPins(t, ac, 12)
+	for _, id := range ids[:5] {
+		s.SetPinStatus(id, creek.PinStatusPinned)
+	}
+
+	it := ac.Pins.List().Status(creek.PinStatusPinned).Limit(2).Iter()
+	n := 0
+	for it.Next() {
+		if it.Pin().Status != string(creek.PinStatusPinned) {
+			t.Errorf("got pin with status %s", it.Pin().Status)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("got %d pins, wanted 5", n)
+	}
+}
@@ -0,0 +1,69 @@
+package creek
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// UploadStats describes the transfer of content during an upload.
+type UploadStats struct {
+	Written int64         // number of bytes of content read from the source
+	Elapsed time.Duration // time taken to send the request and receive a response
+}
+
+// Throughput returns the average upload rate in bytes per second.
+func (s UploadStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Written) / s.Elapsed.Seconds()
+}
+
+// readerSize returns the number of bytes remaining to be read from r, or -1
+// if it cannot be determined.
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len())
+	case *os.File:
+		fi, err := v.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+		pos, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return fi.Size() - pos
+	case interface{ Size() int64 }:
+		return v.Size()
+	}
+	return -1
+}
+
+// progressReader counts the bytes read from an underlying reader, reporting
+// each read to an optional callback.
+type progressReader struct {
+	r       io.Reader
+	total   int64
+	written int64 // accessed atomically
+	fn      func(written, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		written := atomic.AddInt64(&p.written, int64(n))
+		if p.fn != nil {
+			p.fn(written, p.total)
+		}
+	}
+	return n, err
+}
+
+// Written returns the number of bytes read so far.
+func (p *progressReader) Written() int64 {
+	return atomic.LoadInt64(&p.written)
+}
@@ -0,0 +1,144 @@
+package creek_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+// progressRecorder records the calls made to a progress function.
+type progressRecorder struct {
+	mu        sync.Mutex
+	calls     int
+	written   int64
+	total     int64
+	backwards bool // whether written ever decreased
+}
+
+func (p *progressRecorder) progress(written, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if written < p.written {
+		p.backwards = true
+	}
+	p.calls++
+	p.written = written
+	p.total = total
+}
+
+func TestContentAddProgress(t *testing.T) {
+	data := bytes.Repeat([]byte("progress"), 100000)
+	path := filepath.Join(t.TempDir(), "content")
+	if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		add       func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq
+		size      int64 // size of the uploaded content
+		wantTotal int64
+	}{
+		{
+			name: "bytes reader",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAdd("content", bytes.NewReader(data))
+			},
+			size:      int64(len(data)),
+			wantTotal: int64(len(data)),
+		},
+		{
+			name: "file",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				f := openFile(t, path)
+				return ac.ContentAdd("content", f)
+			},
+			size:      int64(len(data)),
+			wantTotal: int64(len(data)),
+		},
+		{
+			name: "partly read file",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				f := openFile(t, path)
+				if _, err := f.Seek(1000, io.SeekStart); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return ac.ContentAdd("content", f)
+			},
+			size:      int64(len(data)) - 1000,
+			wantTotal: int64(len(data)) - 1000,
+		},
+		{
+			name: "file path",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAddFile(path)
+			},
+			size:      int64(len(data)),
+			wantTotal: int64(len(data)),
+		},
+		{
+			name: "unsized reader",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAdd("content", struct{ io.Reader }{bytes.NewReader(data)})
+			},
+			size:      int64(len(data)),
+			wantTotal: -1,
+		},
+		{
+			name: "unsized reader with size",
+			add: func(t *testing.T, ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAdd("content", struct{ io.Reader }{bytes.NewReader(data)}).Size(int64(len(data)))
+			},
+			size:      int64(len(data)),
+			wantTotal: int64(len(data)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := creektest.NewServer()
+			defer s.Close()
+
+			var rec progressRecorder
+			cr := tc.add(t, s.AuthedClient("token")).Progress(rec.progress)
+			if _, err := cr.Send(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			if rec.calls == 0 {
+				t.Fatalf("progress function was not called")
+			}
+			if rec.backwards {
+				t.Errorf("progress went backwards")
+			}
+			if rec.written != tc.size || rec.total != tc.wantTotal {
+				t.Errorf("got last progress %d of %d, wanted %d of %d", rec.written, rec.total, tc.size, tc.wantTotal)
+			}
+			if got := cr.Stats().Written; got != tc.size {
+				t.Errorf("got %d bytes written in stats, wanted %d", got, tc.size)
+			}
+			if contents := s.Contents(); len(contents) != 1 || contents[0].Size != tc.size {
+				t.Errorf("got contents %+v, wanted %d bytes uploaded", contents, tc.size)
+			}
+		})
+	}
+}
+
+func openFile(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,118 @@
+package creek
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EndpointClass identifies a group of API endpoints that share a rate limit.
+type EndpointClass int
+
+const (
+	// EndpointPublic covers endpoints that do not require authentication.
+	EndpointPublic EndpointClass = iota
+
+	// EndpointAuthed covers endpoints that require authentication, other
+	// than uploads.
+	EndpointAuthed
+
+	// EndpointUpload covers endpoints that upload content.
+	EndpointUpload
+)
+
+// RateLimiter is a client side token bucket rate limiter that delays requests
+// so they do not exceed a configured rate. Limits are set separately for each
+// class of endpoint. A RateLimiter is safe for concurrent use and may be shared
+// between clients so that they draw from the same limits.
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[EndpointClass]*tokenBucket
+}
+
+// NewRateLimiter creates a rate limiter with no limits set. Requests for an
+// endpoint class without a limit are not delayed.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[EndpointClass]*tokenBucket),
+	}
+}
+
+// SetLimit limits requests for a class of endpoints to rate requests per
+// second, allowing bursts of up to burst requests. A rate of zero or less
+// removes the limit for the class.
+func (l *RateLimiter) SetLimit(class EndpointClass, rate float64, burst int) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate <= 0 {
+		delete(l.buckets, class)
+		return l
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.buckets[class] = &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+	return l
+}
+
+// Wait blocks until a request for the class of endpoint is permitted or the
+// context is done.
+func (l *RateLimiter) Wait(ctx context.Context, class EndpointClass) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	b, ok := l.buckets[class]
+	if !ok {
+		l.mu.Unlock()
+		return nil
+	}
+	d := b.reserve(time.Now())
+	l.mu.Unlock()
+
+	if err := sleep(ctx, d); err != nil {
+		l.mu.Lock()
+		b.cancel()
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // maximum number of tokens
+	tokens float64 // may be negative when requests are waiting
+	last   time.Time
+}
+
+// reserve takes a token from the bucket and returns how long the caller must
+// wait before the token becomes available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (b *tokenBucket) cancel() {
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,74 @@
+package creek
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := &tokenBucket{rate: 10, burst: 2, tokens: 2, last: start}
+
+	// The burst is available immediately, after which requests are spaced
+	// by the rate.
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := b.reserve(start); got != want {
+			t.Errorf("reservation %d: got wait %v, wanted %v", i, got, want)
+		}
+	}
+
+	// Returning a reservation shortens the wait of the next.
+	b.cancel()
+	if got, want := b.reserve(start), 200*time.Millisecond; got != want {
+		t.Errorf("got wait %v after cancel, wanted %v", got, want)
+	}
+
+	// Tokens accumulate over time but not beyond the burst.
+	later := start.Add(time.Minute)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		if got := b.reserve(later); got != want {
+			t.Errorf("reservation %d after a minute: got wait %v, wanted %v", i, got, want)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter().SetLimit(EndpointUpload, 1, 1)
+	ctx := context.Background()
+
+	// Classes without a limit and nil limiters never wait.
+	var nl *RateLimiter
+	for i := 0; i < 10; i++ {
+		if err := l.Wait(ctx, EndpointPublic); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := nl.Wait(ctx, EndpointUpload); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := l.Wait(ctx, EndpointUpload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The next token is a second away so a shorter deadline fails and the
+	// reservation is returned.
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(tctx, EndpointUpload); err == nil {
+		t.Errorf("got no error, wanted wait to be cut short by the context")
+	}
+	l.mu.Lock()
+	tokens := l.buckets[EndpointUpload].tokens
+	l.mu.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("got %v tokens, wanted cancelled reservation to be returned", tokens)
+	}
+
+	// Removing the limit stops waiting.
+	l.SetLimit(EndpointUpload, 0, 0)
+	if err := l.Wait(tctx, EndpointUpload); err != nil {
+		t.Errorf("unexpected error after removing limit: %v", err)
+	}
+}
@@ -4,21 +4,36 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
+
+	"github.com/ipfs/go-cid"
 )
 
 type req struct {
 	hc      *http.Client
 	ctx     context.Context
-	addr    string
+	base    *url.URL
+	err     error
 	path    string
 	par     params
 	headers headers
+	timeout time.Duration
+	retry   *RetryPolicy
+	limiter *RateLimiter
+	logger  Logger
+	class   EndpointClass
+	tokens  TokenSource // supplies the bearer token for authenticated requests
+
+	// retryPost opts a POST request in to retries regardless of the
+	// retry policy's RetryPost setting.
+	retryPost bool
 }
 
 type headers map[string]string
@@ -41,37 +56,147 @@ func (p params) Encode() string {
 	return url.Values(p).Encode()
 }
 
-func (r *req) url() *url.URL {
-	u := url.URL{
-		Scheme:   "https",
-		Host:     r.addr,
-		Path:     r.path,
-		RawQuery: r.par.Encode(),
+// clone returns a copy of the request that can be modified without
+// affecting the original.
+func (r *req) clone() req {
+	c := *r
+	c.headers = make(headers, len(r.headers))
+	for k, v := range r.headers {
+		c.headers[k] = v
+	}
+	c.par = make(params, len(r.par))
+	for k, vs := range r.par {
+		c.par[k] = append([]string(nil), vs...)
 	}
+	return c
+}
+
+func (r *req) url() *url.URL {
+	u := *r.base
+	u.Path += r.path
+	u.RawQuery = r.par.Encode()
 	return &u
 }
 
+func (r *req) newRequest(method string, body io.Reader) (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return http.NewRequest(method, r.url().String(), body)
+}
+
 func (r *req) do(hr *http.Request) (*http.Response, func(), error) {
+	ctx := hr.Context()
 	if r.ctx != nil {
-		hr = hr.WithContext(r.ctx)
+		ctx = r.ctx
+	}
+	cancel := func() {}
+	if r.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
 	}
+	hr = hr.WithContext(ctx)
+
 	for k, v := range r.headers {
 		hr.Header.Set(k, v)
 	}
 
-	res, err := r.hc.Do(hr)
-	if err != nil {
+	fail := func(err error) (*http.Response, func(), error) {
+		cancel()
 		return nil, func() {}, err
 	}
-	if err := responseError(res); err != nil {
-		return nil, func() {}, err
+
+	var token string
+	if r.tokens != nil {
+		var err error
+		token, err = r.tokens.Token(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		hr.Header.Set("Authorization", "Bearer "+token)
+	}
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		if err := r.limiter.Wait(ctx, r.class); err != nil {
+			return fail(err)
+		}
+
+		res, err := r.hc.Do(hr)
+		if err != nil {
+			err = newRequestError(err, hr)
+		} else {
+			err = responseError(res)
+			if err == nil {
+				closeBody := cleanup(res)
+				return res, func() {
+					closeBody()
+					cancel()
+				}, nil
+			}
+		}
+
+		if res != nil && res.StatusCode == http.StatusUnauthorized && r.tokens != nil && !refreshed && replayable(hr) {
+			// Retry once if the token source has a new token, without
+			// counting the rejected request as an attempt.
+			refreshed = true
+			tok, terr := refreshToken(ctx, r.tokens, token)
+			if terr != nil {
+				r.logf("refresh token: %v", terr)
+			} else if tok != token {
+				r.logf("retrying %s %s with a refreshed token", hr.Method, hr.URL)
+				token = tok
+				hr.Header.Set("Authorization", "Bearer "+token)
+				attempt--
+				if err := resetBody(hr); err != nil {
+					return fail(err)
+				}
+				continue
+			}
+		}
+
+		if !r.retry.shouldRetry(hr, res, err, attempt, r.retryPost) {
+			return fail(err)
+		}
+		delay := r.retry.backoff(res, attempt)
+		r.logf("retrying %s %s in %v after attempt %d failed: %v", hr.Method, hr.URL, delay, attempt, err)
+		if err := sleep(ctx, delay); err != nil {
+			return fail(err)
+		}
+
+		if err := resetBody(hr); err != nil {
+			return fail(err)
+		}
+	}
+}
+
+// replayable reports whether the request can be sent again.
+func replayable(hr *http.Request) bool {
+	return hr.Body == nil || hr.Body == http.NoBody || hr.GetBody != nil
+}
+
+// resetBody replaces the consumed body of a request so that it can be sent
+// again.
+func resetBody(hr *http.Request) error {
+	if hr.GetBody == nil {
+		return nil
+	}
+	body, err := hr.GetBody()
+	if err != nil {
+		return err
 	}
+	hr.Body = body
+	return nil
+}
 
-	return res, cleanup(res), nil
+func (r *req) logf(format string, v ...interface{}) {
+	if r.logger == nil {
+		return
+	}
+	r.logger.Printf(format, v...)
 }
 
 func (r *req) get() (*http.Response, func(), error) {
-	hr, err := http.NewRequest("GET", r.url().String(), nil)
+	hr, err := r.newRequest("GET", nil)
 	if err != nil {
 		return nil, func() {}, err
 	}
@@ -79,14 +204,48 @@ func (r *req) get() (*http.Response, func(), error) {
 }
 
 func (r *req) post(ir io.Reader) (*http.Response, func(), error) {
-	hr, err := http.NewRequest("POST", r.url().String(), ir)
+	hr, err := r.newRequest("POST", ir)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return r.do(hr)
+}
+
+// postBody sends a POST request with a body created by newBody. The body is
+// created again each time the request is retried.
+func (r *req) postBody(newBody func() (io.ReadCloser, error)) (*http.Response, func(), error) {
+	body, err := newBody()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	hr, err := r.newRequest("POST", body)
 	if err != nil {
+		body.Close()
 		return nil, func() {}, err
 	}
+	hr.GetBody = newBody
 	return r.do(hr)
 }
 
 func (r *req) postJSON(data interface{}) (*http.Response, func(), error) {
+	return r.sendJSON("POST", data)
+}
+
+func (r *req) put(ir io.Reader) (*http.Response, func(), error) {
+	hr, err := r.newRequest("PUT", ir)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return r.do(hr)
+}
+
+func (r *req) putJSON(data interface{}) (*http.Response, func(), error) {
+	return r.sendJSON("PUT", data)
+}
+
+// sendJSON sends a request using the supplied method with data encoded as
+// JSON in the body.
+func (r *req) sendJSON(method string, data interface{}) (*http.Response, func(), error) {
 	var body io.Reader
 	if data != nil {
 		var encoded bytes.Buffer
@@ -99,17 +258,25 @@ func (r *req) postJSON(data interface{}) (*http.Response, func(), error) {
 		r.headers["Content-Length"] = strconv.Itoa(encoded.Len())
 	}
 
-	return r.post(body)
+	hr, err := r.newRequest(method, body)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return r.do(hr)
 }
 
 func (r *req) delete() (*http.Response, func(), error) {
-	hr, err := http.NewRequest("DELETE", r.url().String(), nil)
+	hr, err := r.newRequest("DELETE", nil)
 	if err != nil {
 		return nil, func() {}, err
 	}
 	return r.do(hr)
 }
 
+func (r *req) deleteJSON(data interface{}) (*http.Response, func(), error) {
+	return r.sendJSON("DELETE", data)
+}
+
 func cleanup(res *http.Response) func() {
 	return func() {
 		if res == nil || res.Body == nil {
@@ -119,29 +286,123 @@ func cleanup(res *http.Response) func() {
 	}
 }
 
+var (
+	// ErrNotFound is matched by errors for responses with a 404 status.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized is matched by errors for responses with a 401 or 403 status.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrRateLimited is matched by errors for responses with a 429 status.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrServer is matched by errors for responses with a 5xx status.
+	ErrServer = errors.New("server error")
+
+	// ErrDecode is matched by errors caused by a response body that could not
+	// be decoded.
+	ErrDecode = errors.New("unable to decode response")
+)
+
+// ResponseError is the error returned when a request fails, either because it
+// could not be sent, the server responded with a non-2xx status or the
+// response could not be decoded. Use errors.Is with one of the package's
+// sentinel errors to test for common failure conditions.
 type ResponseError struct {
 	Message    string
-	StatusCode int
+	StatusCode int // zero if no response was received
 	URL        string
+	Method     string
+	Header     http.Header // headers of the response, if one was received
+	Body       []byte      // raw body of an error response
+	Err        error       // underlying cause, if any
 }
 
 func (e *ResponseError) Error() string {
 	return fmt.Sprintf("request failed: %s", e.Message)
 }
 
+// Unwrap returns the underlying cause of the error.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error matches one of the package's sentinel errors
+// based on the status code of the response.
+func (e *ResponseError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode/100 == 5
+	}
+	return false
+}
+
+// RetryAfter returns the delay requested by the server's Retry-After header.
+func (e *ResponseError) RetryAfter() (time.Duration, bool) {
+	if e.Header == nil {
+		return 0, false
+	}
+	return retryAfter(e.Header.Get("Retry-After"))
+}
+
+// decodeError wraps an error encountered while decoding a response body so
+// that it matches ErrDecode.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string        { return e.err.Error() }
+func (e *decodeError) Unwrap() error        { return e.err }
+func (e *decodeError) Is(target error) bool { return target == ErrDecode }
+
+// CidMismatchError is returned when the cid of content reported by Estuary
+// does not match the cid that was expected.
+type CidMismatchError struct {
+	Expected cid.Cid
+	Actual   string
+}
+
+func (e *CidMismatchError) Error() string {
+	return fmt.Sprintf("cid mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// newResponseError returns an error for a response whose body could not be
+// decoded.
 func newResponseError(err error, res *http.Response) error {
 	rerr := &ResponseError{
 		Message: err.Error(),
+		Err:     &decodeError{err: err},
 	}
 
 	if res != nil {
 		rerr.StatusCode = res.StatusCode
-		rerr.URL = res.Request.URL.String()
+		rerr.Header = res.Header
+		if res.Request != nil {
+			rerr.URL = res.Request.URL.String()
+			rerr.Method = res.Request.Method
+		}
 	}
 
 	return rerr
 }
 
+// newRequestError returns an error for a request that could not be sent or
+// for which no response was received.
+func newRequestError(err error, hr *http.Request) error {
+	return &ResponseError{
+		Message: err.Error(),
+		URL:     hr.URL.String(),
+		Method:  hr.Method,
+		Err:     err,
+	}
+}
+
 func responseError(res *http.Response) error {
 	if res == nil {
 		return &ResponseError{
@@ -154,7 +415,12 @@ func responseError(res *http.Response) error {
 
 	rerr := &ResponseError{
 		StatusCode: res.StatusCode,
-		URL:        res.Request.URL.String(),
+		Header:     res.Header,
+		Message:    res.Status,
+	}
+	if res.Request != nil {
+		rerr.URL = res.Request.URL.String()
+		rerr.Method = res.Request.Method
 	}
 
 	if res.Body == nil {
@@ -165,8 +431,10 @@ func responseError(res *http.Response) error {
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		rerr.Message = fmt.Sprintf("unable to read response body: %v", err)
+		rerr.Err = err
 		return rerr
 	}
+	rerr.Body = body
 
 	var serr Error
 	err = json.Unmarshal(body, &serr)
@@ -175,6 +443,8 @@ func responseError(res *http.Response) error {
 		return rerr
 	}
 
-	rerr.Message = serr.Error
+	if serr.Error != "" {
+		rerr.Message = serr.Error
+	}
 	return rerr
 }
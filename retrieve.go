@@ -0,0 +1,383 @@
+package creek
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// Retrieve prepares a request to fetch content through the Estuary gateway.
+func (c *Client) Retrieve(root cid.Cid) *RetrieveReq {
+	return newRetrieveReq(&c.config, root)
+}
+
+// Retrieve prepares a request to fetch content through the Estuary gateway.
+func (c *AuthedClient) Retrieve(root cid.Cid) *RetrieveReq {
+	return newRetrieveReq(&c.config, root)
+}
+
+func newRetrieveReq(c *config, root cid.Cid) *RetrieveReq {
+	return &RetrieveReq{
+		req:  c.newReq("/gw/ipfs/"+root.String(), EndpointPublic),
+		root: root,
+	}
+}
+
+type RetrieveReq struct {
+	req
+	root   cid.Cid
+	path   string
+	offset int64
+	length int64 // zero means to the end of the content
+	car    bool
+}
+
+// Context sets the context to be used during this request.
+func (r *RetrieveReq) Context(ctx context.Context) *RetrieveReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Gateway sets the address of the gateway used to fetch the content, such as
+// that of a shuttle holding it. The address takes the same forms as the
+// address passed to New. By default the client's API address is used.
+func (r *RetrieveReq) Gateway(addr string) *RetrieveReq {
+	base, err := parseBaseURL(addr)
+	if err != nil {
+		r.req.err = err
+		return r
+	}
+	r.req.hc, r.req.base = resolveBaseURL(r.req.hc, base)
+	return r
+}
+
+// Path sets a slash separated path within the content to be fetched instead
+// of the content's root.
+func (r *RetrieveReq) Path(p string) *RetrieveReq {
+	r.path = path.Clean("/" + p)
+	if r.path == "/" {
+		r.path = ""
+	}
+	return r
+}
+
+// Range restricts the request to length bytes of the content starting at
+// offset. A length of zero or less fetches the remainder of the content.
+// Ranges cannot be combined with Car.
+func (r *RetrieveReq) Range(offset, length int64) *RetrieveReq {
+	r.offset = offset
+	r.length = length
+	return r
+}
+
+// Car requests the content as a CAR file holding the blocks of its DAG
+// instead of the content itself. The CAR is verified as it is read: the
+// header must name the requested cid as a root, the data of every block must
+// match its cid and the CAR must hold exactly the blocks reachable from the
+// root, or those needed to resolve the path set by Path followed by the DAG
+// beneath it. Blocks must follow a block that links to them, as they do in
+// CARs written by a depth first traversal. Only raw and dag-pb blocks can be
+// verified and paths cannot pass through sharded directories. A
+// *CidMismatchError or other error is returned from Read if verification
+// fails.
+func (r *RetrieveReq) Car() *RetrieveReq {
+	r.car = true
+	return r
+}
+
+// Send sends the prepared request and returns a reader for the content. The
+// caller must close the reader when finished with it. Any timeout set on the
+// client applies to reading the whole of the content.
+func (r *RetrieveReq) Send() (io.ReadCloser, error) {
+	if r.car && (r.offset != 0 || r.length > 0) {
+		return nil, errors.New("byte ranges cannot be requested for car files")
+	}
+	if r.offset < 0 {
+		return nil, fmt.Errorf("invalid range offset: %d", r.offset)
+	}
+
+	r.req.path += r.path
+	if r.car {
+		r.req.par.Set("format", "car")
+		r.req.headers["Accept"] = "application/vnd.ipld.car"
+	}
+	ranged := r.offset > 0 || r.length > 0
+	if ranged {
+		rng := "bytes=" + strconv.FormatInt(r.offset, 10) + "-"
+		if r.length > 0 {
+			rng += strconv.FormatInt(r.offset+r.length-1, 10)
+		}
+		r.req.headers["Range"] = rng
+	}
+
+	res, cleanup, err := r.req.get()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	body := &readCloser{Reader: res.Body, close: cleanup}
+
+	if ranged && res.StatusCode != http.StatusPartialContent {
+		// The gateway ignored the range and sent the whole content.
+		if _, err := io.CopyN(ioutil.Discard, res.Body, r.offset); err != nil {
+			cleanup()
+			if err == io.EOF {
+				return http.NoBody, nil
+			}
+			return nil, err
+		}
+		if r.length > 0 {
+			body.Reader = io.LimitReader(res.Body, r.length)
+		}
+	}
+
+	if r.car {
+		var segments []string
+		if r.path != "" {
+			segments = strings.Split(r.path[1:], "/")
+		}
+		return newCarVerifier(body, r.root, segments), nil
+	}
+	return body, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func()
+}
+
+func (rc *readCloser) Close() error {
+	rc.close()
+	return nil
+}
+
+// carVerifier passes through a CAR file read from an underlying reader,
+// verifying each section before it is passed on.
+type carVerifier struct {
+	rc   io.ReadCloser
+	pr   *io.PipeReader
+	done chan struct{}
+}
+
+func newCarVerifier(rc io.ReadCloser, root cid.Cid, segments []string) *carVerifier {
+	pr, pw := io.Pipe()
+	v := &carVerifier{
+		rc:   rc,
+		pr:   pr,
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(v.done)
+		pw.CloseWithError(verifyCar(bufio.NewReader(rc), pw, root, segments))
+	}()
+	return v
+}
+
+func (v *carVerifier) Read(p []byte) (int, error) {
+	return v.pr.Read(p)
+}
+
+func (v *carVerifier) Close() error {
+	v.pr.Close()
+	err := v.rc.Close()
+	<-v.done
+	return err
+}
+
+// verifyCar copies a CAR file from br to w one section at a time, failing if
+// the CAR is invalid, does not have root as a root, contains a block whose
+// data does not match its cid or does not hold the DAG described by root and
+// the path segments.
+func verifyCar(br *bufio.Reader, w io.Writer, root cid.Cid, segments []string) error {
+	data, err := readCarSection(br, maxCarHeaderSize)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("read car header: %w", err)
+	}
+	h, err := decodeCarHeader(data)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, c := range h.Roots {
+		if c.Equals(root) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &CidMismatchError{Expected: root, Actual: h.Roots[0].String()}
+	}
+	if err := writeCarSection(w, data); err != nil {
+		return err
+	}
+
+	dv := newDagVerifier(root, segments)
+	if root.Prefix().MhType == multihash.IDENTITY {
+		if err := dv.addInline(root); err != nil {
+			return err
+		}
+	}
+	for {
+		data, err := readCarSection(br, maxCarBlockSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read car block: %w", err)
+		}
+		c, err := verifyCarBlock(data)
+		if err != nil {
+			return err
+		}
+		if err := dv.add(c, data[c.ByteLen():]); err != nil {
+			return err
+		}
+		if err := writeCarSection(w, data); err != nil {
+			return err
+		}
+	}
+
+	return dv.complete()
+}
+
+// dagVerifier checks that the blocks of a CAR file form the DAG beneath a
+// root, or the blocks needed to resolve a path beneath the root followed by
+// the DAG beneath the path. Blocks must arrive after a block linking to them,
+// as they do in CAR files written by a depth first traversal.
+type dagVerifier struct {
+	root cid.Cid
+
+	// need maps the cids of blocks that are yet to be seen to the path
+	// segments that remain to be resolved beneath them.
+	need map[cid.Cid][]string
+	seen map[cid.Cid]bool
+}
+
+func newDagVerifier(root cid.Cid, segments []string) *dagVerifier {
+	return &dagVerifier{
+		root: root,
+		need: map[cid.Cid][]string{root: segments},
+		seen: map[cid.Cid]bool{},
+	}
+}
+
+// add records a block whose data has been verified against its cid.
+func (v *dagVerifier) add(c cid.Cid, data []byte) error {
+	segments, ok := v.need[c]
+	if !ok {
+		if v.seen[c] {
+			// Duplicate blocks are allowed.
+			return nil
+		}
+		return fmt.Errorf("car contains block %s that is not reachable from %s", c, v.root)
+	}
+	delete(v.need, c)
+	v.seen[c] = true
+
+	links, err := v.links(c, data, segments)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		if v.seen[l.node.cid] {
+			continue
+		}
+		if l.node.cid.Prefix().MhType == multihash.IDENTITY {
+			v.need[l.node.cid] = l.segments
+			if err := v.addInline(l.node.cid); err != nil {
+				return err
+			}
+			continue
+		}
+		if prev, ok := v.need[l.node.cid]; ok && len(prev) < len(l.segments) {
+			// Keep the shorter path, which needs more of the DAG.
+			continue
+		}
+		v.need[l.node.cid] = l.segments
+	}
+	return nil
+}
+
+// addInline records a block whose data is inlined in its cid. Such blocks
+// may be omitted from the CAR.
+func (v *dagVerifier) addInline(c cid.Cid) error {
+	dmh, err := multihash.Decode(c.Hash())
+	if err != nil {
+		return fmt.Errorf("decode cid %s: %w", c, err)
+	}
+	return v.add(c, dmh.Digest)
+}
+
+// pathLink is a link to a block along with the path segments that remain to
+// be resolved beneath it.
+type pathLink struct {
+	dagLink
+	segments []string
+}
+
+// links returns the links of a block that must be followed to resolve the
+// path segments, or all of its links if no segments remain.
+func (v *dagVerifier) links(c cid.Cid, data []byte, segments []string) ([]pathLink, error) {
+	switch c.Type() {
+	case cid.Raw:
+		if len(segments) > 0 {
+			return nil, fmt.Errorf("resolve path: %s is not a directory", c)
+		}
+		return nil, nil
+	case cid.DagProtobuf:
+	default:
+		return nil, fmt.Errorf("cannot verify links of block %s with codec %#x", c, c.Type())
+	}
+
+	links, ufsData, err := decodeDagPb(data)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %w", c, err)
+	}
+
+	if len(segments) == 0 {
+		pls := make([]pathLink, len(links))
+		for i, l := range links {
+			pls[i] = pathLink{dagLink: l}
+		}
+		return pls, nil
+	}
+
+	typ, err := unixfsType(ufsData)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: %w", c, err)
+	}
+	switch typ {
+	case unixfsDirectory:
+	case unixfsHAMTShard:
+		return nil, fmt.Errorf("resolve path: cannot verify sharded directory %s", c)
+	default:
+		return nil, fmt.Errorf("resolve path: %s is not a directory", c)
+	}
+	for _, l := range links {
+		if l.name == segments[0] {
+			return []pathLink{{dagLink: l, segments: segments[1:]}}, nil
+		}
+	}
+	return nil, fmt.Errorf("resolve path: %s not found in %s", segments[0], c)
+}
+
+// complete checks that every block needed has been seen.
+func (v *dagVerifier) complete() error {
+	for c := range v.need {
+		return fmt.Errorf("car is missing %d blocks reachable from %s, including %s", len(v.need), v.root, c)
+	}
+	return nil
+}
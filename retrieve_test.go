@@ -0,0 +1,150 @@
+package creek
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+// testDag builds a directory holding a multi-block file and returns its root
+// along with every block of the DAG.
+func testDag(t *testing.T) (cid.Cid, map[cid.Cid][]byte) {
+	t.Helper()
+	blocks := map[cid.Cid][]byte{}
+	b := newDagBuilder(DagParams{ChunkSize: 100, MaxLinks: 3}, func(c cid.Cid, data []byte) error {
+		blocks[c] = data
+		return nil
+	})
+
+	file, err := b.file(bytes.NewReader(testContent(1000)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	small, err := b.file(bytes.NewReader(testContent(10)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sub, err := b.directory([]dagLink{{name: "small", node: small}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root, err := b.directory([]dagLink{{name: "file", node: file}, {name: "sub", node: sub}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return root.cid, blocks
+}
+
+// testCar writes a CAR holding the blocks reachable from root in depth
+// first order, skipping any blocks that skip returns true for.
+func testCar(t *testing.T, root cid.Cid, blocks map[cid.Cid][]byte, skip func(cid.Cid) bool) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeCarHeader(&buf, root); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var walk func(c cid.Cid)
+	walk = func(c cid.Cid) {
+		data := blocks[c]
+		if skip == nil || !skip(c) {
+			if err := writeCarBlock(&buf, c, data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if c.Type() != cid.DagProtobuf {
+			return
+		}
+		links, _, err := decodeDagPb(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, l := range links {
+			walk(l.node.cid)
+		}
+	}
+	walk(root)
+	return &buf
+}
+
+func TestVerifyCar(t *testing.T) {
+	root, blocks := testDag(t)
+	leaf, err := rawPrefix.Sum(testContent(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		car := testCar(t, root, blocks, nil)
+		want := car.Bytes()
+		var out bytes.Buffer
+		if err := verifyCar(bufio.NewReader(bytes.NewReader(want)), &out, root, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("car was altered")
+		}
+	})
+
+	t.Run("missing block", func(t *testing.T) {
+		car := testCar(t, root, blocks, func(c cid.Cid) bool { return c.Equals(leaf) })
+		err := verifyCar(bufio.NewReader(car), ioutil.Discard, root, nil)
+		if err == nil || !strings.Contains(err.Error(), "missing") {
+			t.Errorf("got error %v, wanted missing block error", err)
+		}
+	})
+
+	t.Run("unreachable block", func(t *testing.T) {
+		car := testCar(t, root, blocks, nil)
+		other, _ := rawPrefix.Sum([]byte("unrelated"))
+		if err := writeCarBlock(car, other, []byte("unrelated")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err := verifyCar(bufio.NewReader(car), ioutil.Discard, root, nil)
+		if err == nil || !strings.Contains(err.Error(), "not reachable") {
+			t.Errorf("got error %v, wanted unreachable block error", err)
+		}
+	})
+
+	t.Run("corrupt block", func(t *testing.T) {
+		car := testCar(t, root, blocks, nil).Bytes()
+		// The final block is the small file's leaf.
+		car[len(car)-1] ^= 0xff
+		err := verifyCar(bufio.NewReader(bytes.NewReader(car)), ioutil.Discard, root, nil)
+		var cme *CidMismatchError
+		if !errors.As(err, &cme) {
+			t.Errorf("got error %v, wanted *CidMismatchError", err)
+		}
+	})
+
+	t.Run("path", func(t *testing.T) {
+		// A CAR for a path holds the blocks along the path and the DAG
+		// beneath it.
+		var buf bytes.Buffer
+		if err := writeCarHeader(&buf, root); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		links, _, _ := decodeDagPb(blocks[root])
+		sub := links[1].node.cid
+		for _, c := range []cid.Cid{root, sub, leaf} {
+			if err := writeCarBlock(&buf, c, blocks[c]); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		car := buf.Bytes()
+
+		if err := verifyCar(bufio.NewReader(bytes.NewReader(car)), ioutil.Discard, root, []string{"sub", "small"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := verifyCar(bufio.NewReader(bytes.NewReader(car)), ioutil.Discard, root, []string{"sub", "other"}); err == nil {
+			t.Errorf("got no error for missing path")
+		}
+		if err := verifyCar(bufio.NewReader(bytes.NewReader(car)), ioutil.Discard, root, nil); err == nil {
+			t.Errorf("got no error for incomplete dag")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package creek
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests are retried after a transient failure.
+// Requests using the GET, HEAD and DELETE methods are retried automatically.
+// POST requests are only retried when the request body can be replayed and
+// either RetryPost is set or the request opts in, as uploads made with
+// ContentAddFile and ContentAddReaderAt do.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts made for a request,
+	// including the first. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry. The delay doubles with
+	// each subsequent retry, up to MaxBackoff. A random jitter is applied to
+	// every delay.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between attempts.
+	MaxBackoff time.Duration
+
+	// RetryableStatus lists the HTTP status codes that cause a request to be
+	// retried. Network errors are always retried.
+	RetryableStatus []int
+
+	// RetryPost enables retries for POST requests.
+	RetryPost bool
+}
+
+// DefaultRetryPolicy returns a retry policy that makes up to 4 attempts,
+// backing off from half a second to 30 seconds and retrying on 429, 502, 503
+// and 504 responses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		MinBackoff:  500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// shouldRetry reports whether a request that failed on the given attempt
+// should be attempted again.
+func (p *RetryPolicy) shouldRetry(hr *http.Request, res *http.Response, err error, attempt int, retryPost bool) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	if hr.Context().Err() != nil {
+		return false
+	}
+
+	switch hr.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+	case http.MethodPost:
+		if !p.RetryPost && !retryPost {
+			return false
+		}
+		if !replayable(hr) {
+			// body has been consumed and cannot be replayed
+			return false
+		}
+	default:
+		return false
+	}
+
+	if res == nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	for _, code := range p.RetryableStatus {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay to wait before making the next attempt. A
+// Retry-After header in the response takes precedence over the policy.
+func (p *RetryPolicy) backoff(res *http.Response, attempt int) time.Duration {
+	if res != nil {
+		if d, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// Apply jitter of up to half the delay to spread retries from
+	// concurrent clients.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses the value of a Retry-After header, which may be either a
+// number of seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for the duration to elapse or the context to be done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package creek_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+func fastRetries(attempts int) *creek.RetryPolicy {
+	p := creek.DefaultRetryPolicy()
+	p.MaxAttempts = attempts
+	p.MinBackoff = time.Millisecond
+	p.MaxBackoff = 2 * time.Millisecond
+	return p
+}
+
+// countRequests adds a hook to the server that counts requests whose path
+// begins with prefix.
+func countRequests(s *creektest.Server, prefix string) *int32 {
+	var n int32
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			atomic.AddInt32(&n, 1)
+		}
+		return false
+	})
+	return &n
+}
+
+func TestRetry(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	n := countRequests(s, "/health")
+
+	s.Fail("/health", http.StatusServiceUnavailable, 2)
+	if _, err := s.Client().WithRetryPolicy(fastRetries(3)).Health().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(n); got != 3 {
+		t.Errorf("got %d requests, wanted 3", got)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	n := countRequests(s, "/health")
+
+	s.Fail("/health", http.StatusServiceUnavailable, 3)
+	_, err := s.Client().WithRetryPolicy(fastRetries(2)).Health().Send()
+	if !errors.Is(err, creek.ErrServer) {
+		t.Errorf("got error %v, wanted ErrServer", err)
+	}
+	if got := atomic.LoadInt32(n); got != 2 {
+		t.Errorf("got %d requests, wanted 2", got)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	n := countRequests(s, "/health")
+
+	s.Fail("/health", http.StatusInternalServerError, 1)
+	if _, err := s.Client().WithRetryPolicy(fastRetries(3)).Health().Send(); err == nil {
+		t.Errorf("got no error, wanted failure")
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("got %d requests, wanted 1", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+
+	var served int32
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if atomic.AddInt32(&served, 1) > 1 {
+			return false
+		}
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return true
+	})
+
+	start := time.Now()
+	if _, err := s.Client().WithRetryPolicy(fastRetries(2)).Health().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, wanted at least the Retry-After delay of 1s", elapsed)
+	}
+}
+
+func TestRetryPost(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token").WithRetryPolicy(fastRetries(3))
+	n := countRequests(s, "/content/add")
+	data := []byte("content that is uploaded more than once")
+
+	// Streamed uploads cannot be replayed so are not retried.
+	s.Fail("/content/add", http.StatusServiceUnavailable, 1)
+	if _, err := ac.ContentAdd("streamed", bytes.NewReader(data)).Send(); err == nil {
+		t.Errorf("got no error for streamed upload, wanted failure")
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("got %d requests for streamed upload, wanted 1", got)
+	}
+
+	// Uploads from a ReaderAt are replayed.
+	atomic.StoreInt32(n, 0)
+	s.Fail("/content/add", http.StatusServiceUnavailable, 2)
+	added, err := ac.ContentAddReaderAt("replayed", bytes.NewReader(data), int64(len(data))).VerifyCid().Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(n); got != 3 {
+		t.Errorf("got %d requests for replayed upload, wanted 3", got)
+	}
+	contents := s.Contents()
+	if len(contents) != 1 || contents[0].Cid != added.Cid || contents[0].Size != int64(len(data)) {
+		t.Errorf("got contents %+v, wanted the complete upload", contents)
+	}
+}
@@ -0,0 +1,160 @@
+package creek_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+// newShuttle starts a fake server acting as a shuttle for s, accepting the
+// same token, and lists it as the user's upload endpoint.
+func newShuttle(t *testing.T, s *creektest.Server, token string) *creektest.Server {
+	t.Helper()
+	shuttle := creektest.NewServer()
+	shuttle.AddToken(token)
+	s.SetUploadEndpoints(shuttle.URL + "/content/add")
+	return shuttle
+}
+
+func TestViaShuttle(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+	shuttle := newShuttle(t, s, "token")
+	defer shuttle.Close()
+
+	data := bytes.Repeat([]byte("shuttle"), 1000)
+	if _, err := ac.ContentAdd("file", bytes.NewReader(data)).ViaShuttle().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(s.Contents()); n != 0 {
+		t.Errorf("got %d contents on the api host, wanted none", n)
+	}
+	if contents := shuttle.Contents(); len(contents) != 1 || contents[0].Size != int64(len(data)) {
+		t.Errorf("got contents %+v on the shuttle, wanted the complete upload", contents)
+	}
+}
+
+func TestViaShuttleFallback(t *testing.T) {
+	data := bytes.Repeat([]byte("fallback"), 100000)
+
+	testCases := []struct {
+		name    string
+		shuttle func(t *testing.T, s *creektest.Server) (done func())
+		add     func(ac *creek.AuthedClient) *creek.ContentAddReq
+	}{
+		{
+			name: "shuttle failure",
+			shuttle: func(t *testing.T, s *creektest.Server) func() {
+				shuttle := newShuttle(t, s, "token")
+				shuttle.Fail("/content/add", http.StatusServiceUnavailable, 1)
+				var n int32
+				shuttle.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+					if r.URL.Path == "/content/add" {
+						atomic.AddInt32(&n, 1)
+					}
+					return false
+				})
+				return func() {
+					if got := atomic.LoadInt32(&n); got != 1 {
+						t.Errorf("got %d uploads to the shuttle, wanted 1", got)
+					}
+					shuttle.Close()
+				}
+			},
+			add: func(ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAddReaderAt("file", bytes.NewReader(data), int64(len(data)))
+			},
+		},
+		{
+			name: "unreachable shuttle",
+			shuttle: func(t *testing.T, s *creektest.Server) func() {
+				newShuttle(t, s, "token").Close()
+				return func() {}
+			},
+			add: func(ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAdd("file", bytes.NewReader(data))
+			},
+		},
+		{
+			name: "no shuttles",
+			shuttle: func(t *testing.T, s *creektest.Server) func() {
+				return func() {}
+			},
+			add: func(ac *creek.AuthedClient) *creek.ContentAddReq {
+				return ac.ContentAdd("file", bytes.NewReader(data))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := creektest.NewServer()
+			defer s.Close()
+			ac := s.AuthedClient("token")
+			defer tc.shuttle(t, s)()
+
+			cr := tc.add(ac).ViaShuttle().VerifyCid()
+			added, err := cr.Send()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			contents := s.Contents()
+			if len(contents) != 1 || contents[0].Cid != added.Cid || contents[0].Size != int64(len(data)) {
+				t.Errorf("got contents %+v on the api host, wanted the complete upload", contents)
+			}
+			if got := cr.Stats().Written; got != int64(len(data)) {
+				t.Errorf("got %d bytes written, wanted %d", got, len(data))
+			}
+		})
+	}
+}
+
+func TestViaShuttlePartialStream(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+	shuttle := newShuttle(t, s, "token")
+	defer shuttle.Close()
+
+	// The shuttle fails after reading part of the content, which cannot be
+	// read again from a stream.
+	shuttle.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/content/add" {
+			return false
+		}
+		io.CopyN(ioutil.Discard, r.Body, 64<<10)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	})
+	var primary int32
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/content/add" {
+			atomic.AddInt32(&primary, 1)
+		}
+		return false
+	})
+
+	data := bytes.Repeat([]byte("partial"), 1<<20)
+	cr := ac.ContentAdd("file", bytes.NewReader(data)).ViaShuttle()
+	_, err := cr.Send()
+	if !errors.Is(err, creek.ErrServer) {
+		t.Errorf("got error %v, wanted the shuttle's error", err)
+	}
+	if n := atomic.LoadInt32(&primary); n != 0 {
+		t.Errorf("got %d uploads to the api host, wanted none", n)
+	}
+	if written := cr.Stats().Written; written == 0 || written == int64(len(data)) {
+		t.Errorf("got %d bytes written, wanted part of the content", written)
+	}
+	if n := len(s.Contents()) + len(shuttle.Contents()); n != 0 {
+		t.Errorf("got %d contents, wanted none", n)
+	}
+}
@@ -0,0 +1,273 @@
+package creek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A TokenSource supplies the token used to authenticate requests. It is
+// consulted before every request made by an AuthedClient. If Estuary rejects
+// the token, the source is asked for a new one, using Refresh if it
+// implements TokenRefresher and Token otherwise, and the request is retried
+// once if the new token differs. Implementations must be safe for concurrent
+// use.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// A TokenRefresher is a TokenSource that can replace a token that Estuary
+// has rejected. Refresh is passed the rejected token so that a token already
+// replaced by a concurrent call is not replaced again.
+type TokenRefresher interface {
+	TokenSource
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
+// refreshToken obtains a new token from ts to replace one that was rejected.
+func refreshToken(ctx context.Context, ts TokenSource, rejected string) (string, error) {
+	if tr, ok := ts.(TokenRefresher); ok {
+		return tr.Refresh(ctx, rejected)
+	}
+	return ts.Token(ctx)
+}
+
+// Token returns the token the client currently authenticates with, obtained
+// from its TokenSource. An AuthedClient is itself a TokenSource.
+func (c *AuthedClient) Token(ctx context.Context) (string, error) {
+	return c.tokens.Token(ctx)
+}
+
+// Refresh obtains a new token from the client's TokenSource to replace one
+// that was rejected, making an AuthedClient a TokenRefresher.
+func (c *AuthedClient) Refresh(ctx context.Context, rejected string) (string, error) {
+	return refreshToken(ctx, c.tokens, rejected)
+}
+
+// StaticToken returns a TokenSource that always supplies the same token.
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+type staticToken string
+
+func (t staticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// EnvToken returns a TokenSource that supplies the token held in the named
+// environment variable, read each time a token is needed.
+func EnvToken(name string) TokenSource {
+	return envToken(name)
+}
+
+type envToken string
+
+func (t envToken) Token(context.Context) (string, error) {
+	v := strings.TrimSpace(os.Getenv(string(t)))
+	if v == "" {
+		return "", fmt.Errorf("environment variable %s is not set", string(t))
+	}
+	return v, nil
+}
+
+// FileToken returns a TokenSource that supplies the token held in the file at
+// path. The file is read again whenever its size or modification time
+// changes, so a token can be replaced without restarting the program.
+// Leading and trailing white space is ignored.
+func FileToken(path string) TokenSource {
+	return &fileToken{path: path}
+}
+
+type fileToken struct {
+	path    string
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+func (t *fileToken) Token(context.Context) (string, error) {
+	return t.read(false)
+}
+
+// Refresh reads the token from the file even if the file appears unchanged.
+func (t *fileToken) Refresh(context.Context, string) (string, error) {
+	return t.read(true)
+}
+
+func (t *fileToken) read(force bool) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+	if !force && t.token != "" && fi.ModTime().Equal(t.modTime) && fi.Size() == t.size {
+		return t.token, nil
+	}
+
+	data, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return "", fmt.Errorf("read token: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("read token: %s is empty", t.path)
+	}
+
+	t.token = token
+	t.modTime = fi.ModTime()
+	t.size = fi.Size()
+	return token, nil
+}
+
+// RotationOptions controls how a RotatingTokenSource rotates its API key.
+// The zero value uses the default for each setting.
+type RotationOptions struct {
+	// Lifetime is the expiry requested for each new API key. Defaults to 30
+	// days.
+	Lifetime time.Duration
+
+	// RenewBefore is how long before the current key expires that a new key
+	// is created. Defaults to one day.
+	RenewBefore time.Duration
+
+	// OnRotate, if not nil, is called with each new key so that it can be
+	// stored for later use. If it returns an error the old key is not
+	// revoked.
+	OnRotate func(token string, expiry time.Time) error
+}
+
+func (o *RotationOptions) withDefaults() RotationOptions {
+	var v RotationOptions
+	if o != nil {
+		v = *o
+	}
+	if v.Lifetime <= 0 {
+		v.Lifetime = 30 * 24 * time.Hour
+	}
+	if v.RenewBefore <= 0 {
+		v.RenewBefore = 24 * time.Hour
+	}
+	if v.RenewBefore > v.Lifetime/2 {
+		v.RenewBefore = v.Lifetime / 2
+	}
+	return v
+}
+
+// RotatingTokenSource is a TokenSource that replaces its API key with a new
+// one shortly before the key expires and then revokes the old key. Keys are
+// rotated when a token is requested, so a key that is not used before it
+// expires cannot be rotated.
+type RotatingTokenSource struct {
+	client *Client
+	opts   RotationOptions
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewRotatingTokenSource creates a RotatingTokenSource that starts with the
+// supplied token, which expires at expiry, and uses c to create and revoke
+// keys. If expiry is the zero time the token is rotated when it is first
+// used.
+func NewRotatingTokenSource(c *Client, token string, expiry time.Time, opts *RotationOptions) *RotatingTokenSource {
+	return &RotatingTokenSource{
+		client: c,
+		opts:   opts.withDefaults(),
+		token:  token,
+		expiry: expiry,
+	}
+}
+
+// Token returns the current token, rotating it first if it is due to expire.
+func (s *RotatingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		return "", errors.New("no token to rotate")
+	}
+	if time.Until(s.expiry) > s.opts.RenewBefore {
+		return s.token, nil
+	}
+	if err := s.rotate(ctx); err != nil {
+		if s.expiry.IsZero() || time.Now().Before(s.expiry) {
+			// The current key may still be valid so try again next time.
+			s.logf("rotate token: %v", err)
+			return s.token, nil
+		}
+		return "", fmt.Errorf("rotate token: %w", err)
+	}
+	return s.token, nil
+}
+
+// Refresh rotates the key if the rejected token is still the current one and
+// returns the new token. Rotation needs the current key to be valid, so it
+// only succeeds if the key was rejected for another reason, such as having
+// expired earlier than expected.
+func (s *RotatingTokenSource) Refresh(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != rejected {
+		return s.token, nil
+	}
+	if err := s.rotate(ctx); err != nil {
+		return "", fmt.Errorf("rotate token: %w", err)
+	}
+	return s.token, nil
+}
+
+// Expiry returns the time that the current token expires.
+func (s *RotatingTokenSource) Expiry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expiry
+}
+
+// rotate replaces the current key with a new one. The caller must hold s.mu.
+func (s *RotatingTokenSource) rotate(ctx context.Context) error {
+	old := s.client.WithToken(s.token)
+	key, err := old.User.CreateApiKey().Expiry(s.opts.Lifetime).Context(ctx).Send()
+	if err != nil {
+		return err
+	}
+	if key.Token == "" {
+		return errors.New("no token in response")
+	}
+
+	prev := s.token
+	s.token = key.Token
+	s.expiry = key.Expiry
+	if s.expiry.IsZero() {
+		s.expiry = time.Now().Add(s.opts.Lifetime)
+	}
+
+	if s.opts.OnRotate != nil {
+		if err := s.opts.OnRotate(s.token, s.expiry); err != nil {
+			s.logf("rotate token: not revoking old key after OnRotate failed: %v", err)
+			return nil
+		}
+	}
+
+	if err := s.client.WithToken(s.token).User.RevokeApiKey(prev).Context(ctx).Send(); err != nil {
+		s.logf("rotate token: revoke old key: %v", err)
+	}
+	return nil
+}
+
+func (s *RotatingTokenSource) logf(format string, v ...interface{}) {
+	if s.client.logger == nil {
+		return
+	}
+	s.client.logger.Printf(format, v...)
+}
@@ -0,0 +1,105 @@
+package creek_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+func TestFileTokenRefresh(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	s.AddToken("first")
+	s.AddToken("second")
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ac := s.Client().WithTokenSource(creek.FileToken(path))
+	if _, err := ac.Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Replace the token without changing the size or modification time of
+	// the file, so that only a forced refresh sees the new token.
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.RemoveToken("first")
+	if err := ioutil.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chtimes(path, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := ac.Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error after token was replaced: %v", err)
+	}
+	if tok, _ := ac.Token(context.Background()); tok != "second" {
+		t.Errorf("got token %q, wanted %q", tok, "second")
+	}
+}
+
+func TestStaticTokenRejected(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+
+	_, err := s.Client().WithToken("unknown").Viewer().Send()
+	if err == nil {
+		t.Fatalf("got no error for rejected token")
+	}
+}
+
+func TestRotatingTokenSource(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	s.AddToken("initial")
+
+	var stored string
+	ts := creek.NewRotatingTokenSource(s.Client(), "initial", time.Now().Add(time.Hour), &creek.RotationOptions{
+		Lifetime:    48 * time.Hour,
+		RenewBefore: 2 * time.Hour,
+		OnRotate: func(token string, expiry time.Time) error {
+			stored = token
+			return nil
+		},
+	})
+	ac := s.Client().WithTokenSource(ts)
+
+	// The key expires within RenewBefore so it is rotated before use.
+	if _, err := ac.Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tok, err := ts.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok == "initial" || tok != stored {
+		t.Errorf("got token %q and stored %q, wanted a new token to be stored", tok, stored)
+	}
+	if time.Until(ts.Expiry()) < 47*time.Hour {
+		t.Errorf("got expiry %v, wanted the requested lifetime", ts.Expiry())
+	}
+	if _, err := s.Client().WithToken("initial").Viewer().Send(); err == nil {
+		t.Errorf("old token was not revoked")
+	}
+
+	// A rejected token is rotated and the request retried.
+	s.Fail("/viewer", http.StatusUnauthorized, 1)
+	if _, err := ac.Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next, _ := ts.Token(context.Background()); next == tok {
+		t.Errorf("token was not rotated after being rejected")
+	}
+}
@@ -1,6 +1,11 @@
 package creek
 
-import "time"
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+)
 
 type Error struct {
 	Error string `json:"error"`
@@ -67,6 +72,66 @@ type Content struct {
 	DagSplit     bool   `json:"dagSplit"`
 }
 
+// StagingZone holds small content waiting to be aggregated into a deal.
+type StagingZone struct {
+	ContentID  uint      `json:"contentID"` // id of the aggregate content the zone will become
+	ZoneOpened time.Time `json:"zoneOpened"`
+	Contents   []Content `json:"contents"`
+	MinSize    int64     `json:"minSize"`
+	MaxSize    int64     `json:"maxSize"`
+	CurSize    int64     `json:"curSize"`
+	User       uint      `json:"user"`
+	Location   string    `json:"location"`
+}
+
+// Ready reports whether the zone holds enough content to be aggregated.
+func (z *StagingZone) Ready() bool {
+	return z.CurSize >= z.MinSize
+}
+
+// ContentStatus is the status of content and its deals.
+type ContentStatus struct {
+	Content       Content      `json:"content"`
+	Deals         []DealStatus `json:"deals"`
+	FailuresCount int          `json:"failuresCount"`
+}
+
+type DealStatus struct {
+	Deal         ContentDeal       `json:"deal"`
+	Transfer     *TransferStatus   `json:"transfer"`
+	OnChainState *OnChainDealState `json:"onChainState"`
+}
+
+type OnChainDealState struct {
+	SectorStartEpoch int64 `json:"sectorStartEpoch"`
+	LastUpdatedEpoch int64 `json:"lastUpdatedEpoch"`
+	SlashEpoch       int64 `json:"slashEpoch"`
+}
+
+// TransferStatus is the state of the data transfer channel used to send
+// content to a miner.
+type TransferStatus struct {
+	TransferID    string `json:"transferId"`
+	BaseCid       string `json:"baseCid"`
+	SelfPeer      string `json:"selfPeer"`
+	RemotePeer    string `json:"remotePeer"`
+	Status        uint64 `json:"status"`
+	StatusMessage string `json:"statusMessage"`
+	Message       string `json:"message"`
+	Sent          uint64 `json:"sent"`
+	Received      uint64 `json:"received"`
+}
+
+type DealEstimate struct {
+	TotalFil     string             `json:"totalFil"`
+	TotalAttoFil string             `json:"totalAttoFil"`
+	Asks         []*MinerStorageAsk `json:"asks"`
+}
+
+type MadeDeal struct {
+	DealID uint `json:"deal"`
+}
+
 type ContentDeal struct {
 	ID               uint      `json:"id"`
 	Content          uint      `json:"content"`
@@ -128,6 +193,25 @@ type MinerDealFailure struct {
 	MinerVersion string `json:"minerVersion"`
 }
 
+// ClaimedMiner is a miner that has been claimed by a user.
+type ClaimedMiner struct {
+	Addr            string `json:"addr"`
+	Name            string `json:"name"`
+	Suspended       bool   `json:"suspended"`
+	SuspendedReason string `json:"suspendedReason,omitempty"`
+	Version         string `json:"version"`
+}
+
+// MinerClaimMessage is the message that must be signed to claim a miner.
+type MinerClaimMessage struct {
+	HexMsg string `json:"hexmsg"`
+}
+
+// Message returns the decoded message to be signed.
+func (m *MinerClaimMessage) Message() ([]byte, error) {
+	return hex.DecodeString(m.HexMsg)
+}
+
 type MinerStorageAsk struct {
 	Miner         string `json:"miner"`
 	Price         string `json:"price"`
@@ -136,6 +220,152 @@ type MinerStorageAsk struct {
 	MaxPieceSize  uint64 `json:"maxPieceSize"`
 }
 
+// AcceptsPieceSize reports whether the ask accepts deals for pieces of the
+// supplied padded size.
+func (a *MinerStorageAsk) AcceptsPieceSize(size uint64) bool {
+	return size >= a.MinPieceSize && (a.MaxPieceSize == 0 || size <= a.MaxPieceSize)
+}
+
+// PaddedPieceSize returns the padded size of the Filecoin piece needed to
+// hold size bytes of data: the smallest power of two, of at least 128 bytes,
+// whose unpadded capacity of 127/128 of its size is at least size.
+func PaddedPieceSize(size uint64) uint64 {
+	p := uint64(128)
+	for p/128*127 < size {
+		p <<= 1
+	}
+	return p
+}
+
+// ContentAsk is a miner's storage ask for a piece of content.
+type ContentAsk struct {
+	MinerStorageAsk
+	ContentID uint   // Estuary id of the content
+	PieceSize uint64 // padded piece size of the content, estimated from its size
+	Accepted  bool   // whether the ask accepts a deal of the piece size
+}
+
+type Viewer struct {
+	ID         uint         `json:"id"`
+	Username   string       `json:"username"`
+	Perms      int          `json:"perms"`
+	Address    string       `json:"address,omitempty"`
+	Miners     []string     `json:"miners"`
+	AuthExpiry time.Time    `json:"AuthExpiry"`
+	Settings   UserSettings `json:"settings"`
+}
+
+type UserSettings struct {
+	Replication           int           `json:"replication"`
+	Verified              bool          `json:"verified"`
+	DealDuration          int64         `json:"dealDuration"` // in epochs
+	MaxStagingWait        time.Duration `json:"maxStagingWait"`
+	FileStagingThreshold  int64         `json:"fileStagingThreshold"` // content smaller than this is staged for aggregation
+	ContentAddingDisabled bool          `json:"contentAddingDisabled"`
+	DealMakingDisabled    bool          `json:"dealMakingDisabled"`
+	UploadEndpoints       []string      `json:"uploadEndpoints"` // urls of shuttle content add endpoints
+	Flags                 int           `json:"flags"`
+}
+
+type ApiKey struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+type UserStats struct {
+	TotalSize int64 `json:"totalSize"`
+	NumPins   int64 `json:"numPins"`
+}
+
+type AdminUser struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	SpaceUsed int64  `json:"spaceUsed"`
+	NumFiles  int64  `json:"numFiles"`
+}
+
+type AdminStats struct {
+	TotalDealsAttempted  int64 `json:"totalDealsAttempted"`
+	TotalDealsSuccessful int64 `json:"totalDealsSuccessful"`
+	TotalDealsFailed     int64 `json:"totalDealsFailed"`
+	NumMiners            int64 `json:"numMiners"`
+	NumUsers             int64 `json:"numUsers"`
+	NumFiles             int64 `json:"numFiles"`
+	NumRetrievals        int64 `json:"numRetrievals"`
+	NumRetrFailures      int64 `json:"numRetrFailures"`
+	NumStorageFailures   int64 `json:"numStorageFailures"`
+	PinQueueSize         int   `json:"pinQueueSize"`
+}
+
+type Invite struct {
+	Code      string    `json:"code"`
+	CreatedBy string    `json:"createdBy"`
+	ClaimedBy string    `json:"claimedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Shuttle struct {
+	Handle         string         `json:"handle"`
+	Token          string         `json:"token"`
+	LastConnection time.Time      `json:"lastConnection"`
+	Online         bool           `json:"online"`
+	AddrInfo       *peer.AddrInfo `json:"addrInfo"`
+	Address        string         `json:"address"`
+	Hostname       string         `json:"hostname"`
+}
+
+type ShuttleInit struct {
+	Handle string `json:"handle"`
+	Token  string `json:"token"`
+}
+
+// PeeringPeer is a peer that an Estuary node maintains a connection to.
+type PeeringPeer struct {
+	ID        string   `json:"ID"`
+	Addrs     []string `json:"Addrs"`
+	Connected bool     `json:"Connected,omitempty"`
+}
+
+// PinStatus is the status of a pin request as defined by the IPFS Pinning Service API.
+type PinStatus string
+
+const (
+	PinStatusQueued  PinStatus = "queued"
+	PinStatusPinning PinStatus = "pinning"
+	PinStatusPinned  PinStatus = "pinned"
+	PinStatusFailed  PinStatus = "failed"
+)
+
+// TextMatch is the text matching strategy used when filtering pins by name.
+type TextMatch string
+
+const (
+	TextMatchExact              TextMatch = "exact"    // full match, case-sensitive
+	TextMatchExactInsensitive   TextMatch = "iexact"   // full match, case-insensitive
+	TextMatchPartial            TextMatch = "partial"  // partial match, case-sensitive
+	TextMatchPartialInsensitive TextMatch = "ipartial" // partial match, case-insensitive
+)
+
+type Collection struct {
+	UUID        string    `json:"uuid"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	UserID      uint      `json:"userId"`
+}
+
+// CollectionFsEntry is a file or directory in a collection's filesystem.
+type CollectionFsEntry struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // "file" or "directory"
+	Size      int64     `json:"size"`
+	ContentID uint      `json:"contId"`
+	Cid       string    `json:"cid,omitempty"`
+	Dir       string    `json:"dir"`
+	ColUUID   string    `json:"coluuid"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
 type PinList struct {
 	Count   int             `json:"count"`
 	Results []IpfsPinStatus `json:"results"`
@@ -0,0 +1,477 @@
+package creek
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+const (
+	// DefaultChunkSize is the size of the chunks content is split into by
+	// Estuary when building a UnixFS DAG.
+	DefaultChunkSize = 1 << 20
+
+	// DefaultMaxLinks is the maximum number of links in each node of the
+	// UnixFS DAGs built by Estuary.
+	DefaultMaxLinks = 1024
+)
+
+// DagParams controls how content is split into chunks and arranged into a
+// UnixFS DAG when computing its cid locally.
+type DagParams struct {
+	ChunkSize int // size of each leaf, defaults to DefaultChunkSize
+	MaxLinks  int // maximum number of children of each node, defaults to DefaultMaxLinks
+}
+
+// ComputeCid reads content from r until EOF and returns the root cid of the
+// UnixFS DAG that Estuary builds when the content is uploaded. Content is
+// split into fixed size raw leaves arranged in a balanced DAG of CIDv1 nodes
+// hashed with sha2-256.
+func ComputeCid(r io.Reader) (cid.Cid, error) {
+	return DagParams{}.ComputeCid(r)
+}
+
+// ComputeCid reads content from r until EOF and returns the root cid of the
+// UnixFS DAG built using the parameters.
+func (p DagParams) ComputeCid(r io.Reader) (cid.Cid, error) {
+	b := newDagBuilder(p, nil)
+	n, err := b.file(r)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return n.cid, nil
+}
+
+// cidTee passes content read from an underlying reader through unchanged
+// while computing the cid of the content.
+type cidTee struct {
+	r      io.Reader
+	pw     *io.PipeWriter
+	result chan cidResult
+}
+
+type cidResult struct {
+	cid cid.Cid
+	err error
+}
+
+func newCidTee(r io.Reader, p DagParams) *cidTee {
+	pr, pw := io.Pipe()
+	t := &cidTee{
+		r:      r,
+		pw:     pw,
+		result: make(chan cidResult, 1),
+	}
+	go func() {
+		c, err := p.ComputeCid(pr)
+		pr.CloseWithError(err)
+		t.result <- cidResult{cid: c, err: err}
+	}()
+	return t
+}
+
+func (t *cidTee) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	if n > 0 {
+		if _, werr := t.pw.Write(b[:n]); werr != nil {
+			return n, werr
+		}
+	}
+	if err == io.EOF {
+		t.pw.Close()
+	} else if err != nil {
+		t.pw.CloseWithError(err)
+	}
+	return n, err
+}
+
+// Abort stops the computation of the cid when the content will not be read
+// to the end, causing Cid to return err.
+func (t *cidTee) Abort(err error) {
+	t.pw.CloseWithError(err)
+}
+
+// Cid returns the cid of the content once the underlying reader has been
+// read to the end or has failed.
+func (t *cidTee) Cid() (cid.Cid, error) {
+	res := <-t.result
+	return res.cid, res.err
+}
+
+var (
+	rawPrefix = cid.Prefix{
+		Version:  1,
+		Codec:    cid.Raw,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}
+	dagPbPrefix = cid.Prefix{
+		Version:  1,
+		Codec:    cid.DagProtobuf,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}
+)
+
+// UnixFS data types
+const (
+	unixfsDirectory = 1
+	unixfsFile      = 2
+	unixfsHAMTShard = 5
+)
+
+// dagNode describes a node of a DAG that has been built.
+type dagNode struct {
+	cid      cid.Cid
+	fileSize uint64 // number of bytes of file content beneath the node
+	tsize    uint64 // cumulative size of the serialized node and its descendants
+}
+
+// dagLink is a named link from a node to a child.
+type dagLink struct {
+	name string
+	node dagNode
+}
+
+// dagBuilder builds UnixFS DAGs using the same layout as go-unixfs's balanced
+// layout with raw leaves.
+type dagBuilder struct {
+	chunkSize int
+	maxLinks  int
+
+	// emit is called with every block that is built, if not nil.
+	emit func(c cid.Cid, data []byte) error
+
+	// chunker state for the file currently being built
+	r    io.Reader
+	next []byte
+	err  error
+}
+
+func newDagBuilder(p DagParams, emit func(c cid.Cid, data []byte) error) *dagBuilder {
+	b := &dagBuilder{
+		chunkSize: p.ChunkSize,
+		maxLinks:  p.MaxLinks,
+		emit:      emit,
+	}
+	if b.chunkSize <= 0 {
+		b.chunkSize = DefaultChunkSize
+	}
+	if b.maxLinks < 2 {
+		b.maxLinks = DefaultMaxLinks
+	}
+	return b
+}
+
+// file builds a DAG for the content read from r.
+func (b *dagBuilder) file(r io.Reader) (dagNode, error) {
+	b.r = r
+	b.next = nil
+	b.err = nil
+	b.prefetch()
+
+	if b.done() {
+		if b.err != nil {
+			return dagNode{}, b.err
+		}
+		return b.leaf(nil)
+	}
+
+	root, err := b.leaf(b.chunk())
+	if err != nil {
+		return dagNode{}, err
+	}
+
+	for depth := 1; !b.done(); depth++ {
+		root, err = b.fill([]dagNode{root}, depth)
+		if err != nil {
+			return dagNode{}, err
+		}
+	}
+	if b.err != nil {
+		return dagNode{}, b.err
+	}
+
+	return root, nil
+}
+
+// fill adds children to a node of the given depth until it is full or the
+// content is exhausted.
+func (b *dagBuilder) fill(children []dagNode, depth int) (dagNode, error) {
+	for len(children) < b.maxLinks && !b.done() {
+		var child dagNode
+		var err error
+		if depth == 1 {
+			child, err = b.leaf(b.chunk())
+		} else {
+			child, err = b.fill(nil, depth-1)
+		}
+		if err != nil {
+			return dagNode{}, err
+		}
+		children = append(children, child)
+	}
+	if b.err != nil {
+		return dagNode{}, b.err
+	}
+
+	var fileSize uint64
+	blocksizes := make([]uint64, len(children))
+	links := make([]dagLink, len(children))
+	for i, c := range children {
+		fileSize += c.fileSize
+		blocksizes[i] = c.fileSize
+		links[i] = dagLink{node: c}
+	}
+
+	n, err := b.node(links, unixfsData(unixfsFile, &fileSize, blocksizes))
+	if err != nil {
+		return dagNode{}, err
+	}
+	n.fileSize = fileSize
+	return n, nil
+}
+
+// directory builds a UnixFS directory node linking to the supplied entries.
+func (b *dagBuilder) directory(entries []dagLink) (dagNode, error) {
+	sorted := make([]dagLink, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+	return b.node(sorted, unixfsData(unixfsDirectory, nil, nil))
+}
+
+// leaf builds a raw leaf block.
+func (b *dagBuilder) leaf(data []byte) (dagNode, error) {
+	c, err := rawPrefix.Sum(data)
+	if err != nil {
+		return dagNode{}, err
+	}
+	if b.emit != nil {
+		if err := b.emit(c, data); err != nil {
+			return dagNode{}, err
+		}
+	}
+	return dagNode{
+		cid:      c,
+		fileSize: uint64(len(data)),
+		tsize:    uint64(len(data)),
+	}, nil
+}
+
+// node builds a dag-pb block with the supplied links and data.
+func (b *dagBuilder) node(links []dagLink, data []byte) (dagNode, error) {
+	block := encodeDagPb(links, data)
+	c, err := dagPbPrefix.Sum(block)
+	if err != nil {
+		return dagNode{}, err
+	}
+	if b.emit != nil {
+		if err := b.emit(c, block); err != nil {
+			return dagNode{}, err
+		}
+	}
+
+	tsize := uint64(len(block))
+	for _, l := range links {
+		tsize += l.node.tsize
+	}
+	return dagNode{cid: c, tsize: tsize}, nil
+}
+
+// prefetch reads the next chunk of content so that done can report whether
+// any content remains.
+func (b *dagBuilder) prefetch() {
+	if b.err != nil {
+		return
+	}
+	buf := make([]byte, b.chunkSize)
+	n, err := io.ReadFull(b.r, buf)
+	switch {
+	case err == nil, errors.Is(err, io.ErrUnexpectedEOF):
+		b.next = buf[:n]
+	case errors.Is(err, io.EOF):
+		b.next = nil
+	default:
+		b.next = nil
+		b.err = err
+	}
+}
+
+func (b *dagBuilder) done() bool {
+	return b.next == nil
+}
+
+func (b *dagBuilder) chunk() []byte {
+	c := b.next
+	b.prefetch()
+	return c
+}
+
+// encodeDagPb encodes a dag-pb node. Links are written before data and every
+// link includes a name, even if empty, matching go-merkledag.
+func encodeDagPb(links []dagLink, data []byte) []byte {
+	var buf []byte
+	for _, l := range links {
+		var lb []byte
+		lb = appendBytesField(lb, 1, l.node.cid.Bytes())
+		lb = appendBytesField(lb, 2, []byte(l.name))
+		lb = appendVarintField(lb, 3, l.node.tsize)
+		buf = appendBytesField(buf, 2, lb)
+	}
+	if data != nil {
+		buf = appendBytesField(buf, 1, data)
+	}
+	return buf
+}
+
+// decodeDagPb decodes a dag-pb node, returning its links and data. Only the
+// cid and name of each link are set.
+func decodeDagPb(block []byte) ([]dagLink, []byte, error) {
+	var links []dagLink
+	var data []byte
+	err := readFields(block, func(field int, v []byte) error {
+		switch field {
+		case 1:
+			data = v
+		case 2:
+			var l dagLink
+			err := readFields(v, func(field int, v []byte) error {
+				switch field {
+				case 1:
+					c, err := cid.Cast(v)
+					if err != nil {
+						return fmt.Errorf("invalid link cid: %w", err)
+					}
+					l.node.cid = c
+				case 2:
+					l.name = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if !l.node.cid.Defined() {
+				return errors.New("link has no cid")
+			}
+			links = append(links, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode dag-pb node: %w", err)
+	}
+	return links, data, nil
+}
+
+// unixfsType returns the type of a UnixFS node from its encoded data field.
+func unixfsType(data []byte) (uint64, error) {
+	var typ uint64
+	found := false
+	err := readFields(data, func(field int, v []byte) error {
+		if field == 1 {
+			n, err := readVarint(v)
+			if err != nil {
+				return err
+			}
+			typ, found = n, true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("decode unixfs data: %w", err)
+	}
+	if !found {
+		return 0, errors.New("decode unixfs data: no type")
+	}
+	return typ, nil
+}
+
+// readFields calls fn with the number and value of each field of an encoded
+// protobuf message. Varint fields are passed as their encoded bytes.
+func readFields(buf []byte, fn func(field int, v []byte) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return errors.New("invalid field key")
+		}
+		buf = buf[n:]
+
+		var v []byte
+		switch key & 7 {
+		case 0: // varint
+			_, n := binary.Uvarint(buf)
+			if n <= 0 {
+				return errors.New("invalid varint")
+			}
+			v, buf = buf[:n], buf[n:]
+		case 1: // 64-bit
+			if len(buf) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			v, buf = buf[:8], buf[8:]
+		case 2: // length delimited
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || l > uint64(len(buf)-n) {
+				return errors.New("invalid field length")
+			}
+			v, buf = buf[n:n+int(l)], buf[n+int(l):]
+		case 5: // 32-bit
+			if len(buf) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			v, buf = buf[:4], buf[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&7)
+		}
+
+		if err := fn(int(key>>3), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readVarint(v []byte) (uint64, error) {
+	n, l := binary.Uvarint(v)
+	if l <= 0 {
+		return 0, errors.New("invalid varint")
+	}
+	return n, nil
+}
+
+// unixfsData encodes the data field of a UnixFS node.
+func unixfsData(typ uint64, fileSize *uint64, blocksizes []uint64) []byte {
+	buf := appendVarintField(nil, 1, typ)
+	if fileSize != nil {
+		buf = appendVarintField(buf, 3, *fileSize)
+	}
+	for _, bs := range blocksizes {
+		buf = appendVarintField(buf, 4, bs)
+	}
+	return buf
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	buf = appendVarint(buf, uint64(field)<<3)
+	return appendVarint(buf, v)
+}
+
+func appendBytesField(buf []byte, field int, v []byte) []byte {
+	buf = appendVarint(buf, uint64(field)<<3|2)
+	buf = appendVarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
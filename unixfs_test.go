@@ -0,0 +1,122 @@
+package creek
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+// Expected cids were computed with boxo's balanced importer using raw leaves
+// and a CIDv1 sha2-256 cid builder.
+
+// testContent returns n bytes of deterministic content.
+func testContent(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestComputeCid(t *testing.T) {
+	testCases := []struct {
+		size int
+		want string
+	}{
+		{size: 0, want: "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"},
+		{size: 10, want: "bafkreia7qjnkf4acb3347eo7umg2izunpeof2sbe7shecnklrhwak6k2wm"},
+		{size: 32, want: "bafkreiddbxgsszwegntjcesujc53ew2p6qjkjhdtfwzmrk6bxbmbxvyq3u"},
+		{size: 33, want: "bafkreic5r7h67kno5nyr7ohndzfx2xektox2i3uoo3tivimk3ts2cdpwvm"},
+		{size: 1<<20 + 5, want: "bafybeifzme2lsvradjrpn7lsush6isv3piec2qqiga2jhmd4bhxtjcdele"},
+		{size: 2 << 20, want: "bafybeihakzyqh6ih4iwgqily25hqovthaujdkbcfybornyhhbpoa63jwri"},
+		{size: 3<<20 + 17, want: "bafybeiengxwx4jlbvzg6ykpre7r5jxqvalq7yu4qbcj2w3gzacac33gjza"},
+	}
+
+	for _, tc := range testCases {
+		got, err := ComputeCid(bytes.NewReader(testContent(tc.size)))
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", tc.size, err)
+		}
+		if got.String() != tc.want {
+			t.Errorf("size %d: got %s, wanted %s", tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestComputeCidDeepDag(t *testing.T) {
+	// Small chunks and few links force a DAG several layers deep.
+	p := DagParams{ChunkSize: 100, MaxLinks: 3}
+	testCases := []struct {
+		size int
+		want string
+	}{
+		{size: 1000, want: "bafybeigaa5c6es3yqfu4lag76ihqyoibyxyiad4dkoq4qqmxtqel6u4xqe"},
+		{size: 4000, want: "bafybeiewllxrfjoqnsy2a6pawmysffrdnchvayym5ct2xgani66vsja7uq"},
+	}
+
+	for _, tc := range testCases {
+		got, err := p.ComputeCid(bytes.NewReader(testContent(tc.size)))
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", tc.size, err)
+		}
+		if got.String() != tc.want {
+			t.Errorf("size %d: got %s, wanted %s", tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestDirectoryCid(t *testing.T) {
+	b := newDagBuilder(DagParams{}, nil)
+
+	empty, err := b.directory(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354"; empty.cid.String() != want {
+		t.Errorf("empty directory: got %s, wanted %s", empty.cid, want)
+	}
+
+	small, err := b.file(bytes.NewReader(testContent(10)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	large, err := b.file(bytes.NewReader(testContent(1<<20 + 5)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir, err := b.directory([]dagLink{
+		{name: "b.txt", node: small},
+		{name: "empty", node: empty},
+		{name: "a.txt", node: large},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "bafybeihnomkdq3mq35uqvva3h5hjaiov5q6lwpi2flt3w67j45gz2oqchi"; dir.cid.String() != want {
+		t.Errorf("directory: got %s, wanted %s", dir.cid, want)
+	}
+}
+
+func TestCidTee(t *testing.T) {
+	data := testContent(3<<20 + 17)
+	tee := newCidTee(bytes.NewReader(data), DagParams{})
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(tee); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("tee altered content")
+	}
+
+	got, err := tee.Cid()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := cid.Decode("bafybeiengxwx4jlbvzg6ykpre7r5jxqvalq7yu4qbcj2w3gzacac33gjza")
+	if !got.Equals(want) {
+		t.Errorf("got %s, wanted %s", got, want)
+	}
+}
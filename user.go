@@ -0,0 +1,199 @@
+package creek
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// UserServices provides access to user account related API services.
+type UserServices struct {
+	client *AuthedClient
+}
+
+func NewUserServices(a *AuthedClient) *UserServices {
+	return &UserServices{client: a}
+}
+
+// ApiKeys prepares a request for a list of the user's API keys.
+func (s *UserServices) ApiKeys() *UserServicesApiKeysReq {
+	return &UserServicesApiKeysReq{
+		client: s.client,
+		req:    s.client.newReq("/user/api-keys"),
+	}
+}
+
+type UserServicesApiKeysReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *UserServicesApiKeysReq) Context(ctx context.Context) *UserServicesApiKeysReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns a list of API keys.
+func (r *UserServicesApiKeysReq) Send() ([]ApiKey, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []ApiKey
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
+
+// CreateApiKey prepares a request to create a new API key. Estuary sets a
+// default expiry unless Expiry or NoExpiry is used.
+func (s *UserServices) CreateApiKey() *UserServicesCreateApiKeyReq {
+	return &UserServicesCreateApiKeyReq{
+		client: s.client,
+		req:    s.client.newReq("/user/api-keys"),
+	}
+}
+
+type UserServicesCreateApiKeyReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *UserServicesCreateApiKeyReq) Context(ctx context.Context) *UserServicesCreateApiKeyReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Expiry sets how long the key remains valid after it is created.
+func (r *UserServicesCreateApiKeyReq) Expiry(d time.Duration) *UserServicesCreateApiKeyReq {
+	r.req.par.Set("expiry", d.String())
+	return r
+}
+
+// NoExpiry requests a key that does not expire.
+func (r *UserServicesCreateApiKeyReq) NoExpiry() *UserServicesCreateApiKeyReq {
+	r.req.par.Set("expiry", "false")
+	return r
+}
+
+// Send sends the prepared request and returns the new API key.
+func (r *UserServicesCreateApiKeyReq) Send() (*ApiKey, error) {
+	res, cleanup, err := r.req.post(nil)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data ApiKey
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// RevokeApiKey prepares a request to revoke an API key.
+func (s *UserServices) RevokeApiKey(key string) *UserServicesRevokeApiKeyReq {
+	return &UserServicesRevokeApiKeyReq{
+		client: s.client,
+		req:    s.client.newReq("/user/api-keys/" + url.PathEscape(key)),
+	}
+}
+
+type UserServicesRevokeApiKeyReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *UserServicesRevokeApiKeyReq) Context(ctx context.Context) *UserServicesRevokeApiKeyReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request.
+func (r *UserServicesRevokeApiKeyReq) Send() error {
+	_, cleanup, err := r.req.delete()
+	defer cleanup()
+
+	return err
+}
+
+// Stats prepares a request for statistics about the user's stored content.
+func (s *UserServices) Stats() *UserServicesStatsReq {
+	return &UserServicesStatsReq{
+		client: s.client,
+		req:    s.client.newReq("/user/stats"),
+	}
+}
+
+type UserServicesStatsReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *UserServicesStatsReq) Context(ctx context.Context) *UserServicesStatsReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the user's statistics.
+func (r *UserServicesStatsReq) Send() (*UserStats, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data UserStats
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return &data, nil
+}
+
+// Export prepares a request for an export of all the data Estuary holds
+// about the user.
+func (s *UserServices) Export() *UserServicesExportReq {
+	return &UserServicesExportReq{
+		client: s.client,
+		req:    s.client.newReq("/user/export"),
+	}
+}
+
+type UserServicesExportReq struct {
+	req
+	client *AuthedClient
+}
+
+// Context sets the context to be used during this request.
+func (r *UserServicesExportReq) Context(ctx context.Context) *UserServicesExportReq {
+	r.req.ctx = ctx
+	return r
+}
+
+// Send sends the prepared request and returns the exported data. The JSON
+// document is returned as sent by Estuary so that it can be archived as is.
+func (r *UserServicesExportReq) Send() (json.RawMessage, error) {
+	res, cleanup, err := r.req.get()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var data json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, newResponseError(err, res)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,35 @@
+package creek_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+func TestApiKeys(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	key, err := ac.User.CreateApiKey().Expiry(time.Hour).Send()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Until(key.Expiry); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("got expiry %v, wanted an hour from now", key.Expiry)
+	}
+
+	kc := s.Client().WithToken(key.Token)
+	if _, err := kc.Viewer().Send(); err != nil {
+		t.Fatalf("unexpected error using new key: %v", err)
+	}
+	if err := ac.User.RevokeApiKey(key.Token).Send(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := kc.Viewer().Send(); !errors.Is(err, creek.ErrUnauthorized) {
+		t.Errorf("got error %v using revoked key, wanted ErrUnauthorized", err)
+	}
+}
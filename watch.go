@@ -0,0 +1,167 @@
+package creek
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WaitOptions controls how often the status of a pin is polled while waiting
+// for it to complete. The zero value uses the default for each setting.
+type WaitOptions struct {
+	// MinInterval is the delay between the first two polls and after any
+	// change in status. Defaults to one second.
+	MinInterval time.Duration
+
+	// MaxInterval is the maximum delay between polls. Defaults to 30 seconds.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor the delay is increased by after each poll that
+	// sees no change in status. Defaults to 1.5.
+	Multiplier float64
+}
+
+func (o *WaitOptions) withDefaults() WaitOptions {
+	var v WaitOptions
+	if o != nil {
+		v = *o
+	}
+	if v.MinInterval <= 0 {
+		v.MinInterval = time.Second
+	}
+	if v.MaxInterval <= 0 {
+		v.MaxInterval = 30 * time.Second
+	}
+	if v.MaxInterval < v.MinInterval {
+		v.MaxInterval = v.MinInterval
+	}
+	if v.Multiplier < 1 {
+		v.Multiplier = 1.5
+	}
+	return v
+}
+
+// PinFailedError is returned when a pin being waited on reaches the failed status.
+type PinFailedError struct {
+	Status *IpfsPinStatus
+}
+
+func (e *PinFailedError) Error() string {
+	return fmt.Sprintf("pin %s failed", e.Status.RequestId)
+}
+
+// WaitError is returned when waiting for a pin stops before the pin completed,
+// for example because the context expired.
+type WaitError struct {
+	RequestId string
+	Last      *IpfsPinStatus // last status seen, nil if none was received
+	Err       error
+}
+
+func (e *WaitError) Error() string {
+	if e.Last != nil {
+		return fmt.Sprintf("waiting for pin %s (last status %s): %v", e.RequestId, e.Last.Status, e.Err)
+	}
+	return fmt.Sprintf("waiting for pin %s: %v", e.RequestId, e.Err)
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// PinEvent reports a change in the status of a pin being watched.
+type PinEvent struct {
+	RequestId string
+	Status    *IpfsPinStatus // latest status of the pin, nil if none was received
+	Done      bool           // true for the last event sent for the pin
+	Err       error          // set on the last event if the pin failed or could not be watched
+}
+
+// Wait polls the status of a pin until it is pinned or failed. It returns the
+// final status of the pin, a *PinFailedError if the pin failed or a
+// *WaitError if the context is done before the pin completed. Nil options
+// use the defaults.
+func (s *PinServices) Wait(ctx context.Context, requestId string, opts *WaitOptions) (*IpfsPinStatus, error) {
+	return s.poll(ctx, requestId, opts, nil)
+}
+
+// Watch polls the status of several pins until each one is pinned or failed,
+// sending an event on the returned channel whenever the status of a pin
+// changes. The final event for each pin has Done set and carries the same
+// error that Wait would return. The channel is closed once every pin has
+// completed and must be drained until then.
+func (s *PinServices) Watch(ctx context.Context, requestIds []string, opts *WaitOptions) <-chan PinEvent {
+	events := make(chan PinEvent, len(requestIds))
+
+	var wg sync.WaitGroup
+	for _, id := range requestIds {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			st, err := s.poll(ctx, id, opts, func(st *IpfsPinStatus) {
+				select {
+				case events <- PinEvent{RequestId: id, Status: st}:
+				case <-ctx.Done():
+				}
+			})
+			switch e := err.(type) {
+			case *WaitError:
+				st = e.Last
+			case *PinFailedError:
+				st = e.Status
+			}
+			events <- PinEvent{RequestId: id, Status: st, Done: true, Err: err}
+		}(id)
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	return events
+}
+
+// poll requests the status of a pin until it completes, calling onChange
+// whenever an intermediate status differs from the previous one.
+func (s *PinServices) poll(ctx context.Context, requestId string, opts *WaitOptions, onChange func(*IpfsPinStatus)) (*IpfsPinStatus, error) {
+	o := opts.withDefaults()
+	interval := o.MinInterval
+
+	var last *IpfsPinStatus
+	for {
+		st, err := s.Get(requestId).Context(ctx).Send()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, &WaitError{RequestId: requestId, Last: last, Err: ctx.Err()}
+			}
+			return nil, &WaitError{RequestId: requestId, Last: last, Err: err}
+		}
+
+		switch PinStatus(st.Status) {
+		case PinStatusPinned:
+			return st, nil
+		case PinStatusFailed:
+			return nil, &PinFailedError{Status: st}
+		}
+
+		if last == nil || last.Status != st.Status {
+			interval = o.MinInterval
+			if onChange != nil {
+				onChange(st)
+			}
+		} else {
+			interval = time.Duration(float64(interval) * o.Multiplier)
+			if interval > o.MaxInterval {
+				interval = o.MaxInterval
+			}
+		}
+		last = st
+
+		if err := sleep(ctx, interval); err != nil {
+			return nil, &WaitError{RequestId: requestId, Last: last, Err: err}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package creek_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iand/creek"
+	"github.com/iand/creek/creektest"
+)
+
+var fastPolling = &creek.WaitOptions{MinInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+
+func TestPinWait(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+	s.AdvancePins(true)
+
+	id := addPins(t, ac, 1)[0]
+	st, err := ac.Pins.Wait(context.Background(), id, fastPolling)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.Status != string(creek.PinStatusPinned) {
+		t.Errorf("got status %s, wanted pinned", st.Status)
+	}
+}
+
+func TestPinWaitFailed(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	id := addPins(t, ac, 1)[0]
+	s.SetPinStatus(id, creek.PinStatusFailed)
+
+	_, err := ac.Pins.Wait(context.Background(), id, fastPolling)
+	var pfe *creek.PinFailedError
+	if !errors.As(err, &pfe) {
+		t.Fatalf("got error %v, wanted *PinFailedError", err)
+	}
+	if pfe.Status.RequestId != id {
+		t.Errorf("got request id %s, wanted %s", pfe.Status.RequestId, id)
+	}
+}
+
+func TestPinWaitTimeout(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+
+	// Pins stay queued unless advanced.
+	id := addPins(t, ac, 1)[0]
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := ac.Pins.Wait(ctx, id, fastPolling)
+	var we *creek.WaitError
+	if !errors.As(err, &we) {
+		t.Fatalf("got error %v, wanted *WaitError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, wanted deadline exceeded", err)
+	}
+	if we.Last == nil || we.Last.Status != string(creek.PinStatusQueued) {
+		t.Errorf("got last status %+v, wanted queued", we.Last)
+	}
+}
+
+func TestPinWatch(t *testing.T) {
+	s := creektest.NewServer()
+	defer s.Close()
+	ac := s.AuthedClient("token")
+	s.AdvancePins(true)
+
+	ids := addPins(t, ac, 3)
+	statuses := map[string][]string{}
+	for ev := range ac.Pins.Watch(context.Background(), ids, fastPolling) {
+		if ev.Err != nil {
+			t.Errorf("pin %s: unexpected error: %v", ev.RequestId, ev.Err)
+			continue
+		}
+		statuses[ev.RequestId] = append(statuses[ev.RequestId], ev.Status.Status)
+		if ev.Done && ev.Status.Status != string(creek.PinStatusPinned) {
+			t.Errorf("pin %s: got final status %s, wanted pinned", ev.RequestId, ev.Status.Status)
+		}
+	}
+
+	for _, id := range ids {
+		got := fmt.Sprint(statuses[id])
+		if want := "[queued pinning pinned]"; got != want {
+			t.Errorf("pin %s: got statuses %s, wanted %s", id, got, want)
+		}
+	}
+}